@@ -0,0 +1,58 @@
+package main
+
+import (
+	"math"
+	"time"
+)
+
+// arrivalSchedule draws inter-arrival gaps for the producer: exponential
+// gaps give a Poisson process, uniform gaps are spread over [0, 2*mean].
+type arrivalSchedule struct {
+	mode   string
+	mean   time.Duration
+	random *lockedRand
+
+	last      time.Time
+	intervals []time.Duration
+}
+
+func newArrivalSchedule(mode string, mean time.Duration, random *lockedRand) *arrivalSchedule {
+	return &arrivalSchedule{mode: mode, mean: mean, random: random}
+}
+
+func (schedule *arrivalSchedule) next() time.Duration {
+	u := schedule.random.Float64()
+	if schedule.mode == "poisson" {
+		return time.Duration(-math.Log(1-u) * float64(schedule.mean))
+	}
+	return time.Duration(u * 2 * float64(schedule.mean))
+}
+
+// wait sleeps for the next gap and records the gap actually achieved, which
+// is longer than drawn when the queue pushes back.
+func (schedule *arrivalSchedule) wait() {
+	if !schedule.last.IsZero() {
+		time.Sleep(time.Until(schedule.last.Add(schedule.next())))
+	}
+	now := time.Now()
+	if !schedule.last.IsZero() {
+		schedule.intervals = append(schedule.intervals, now.Sub(schedule.last))
+	}
+	schedule.last = now
+}
+
+func (schedule *arrivalSchedule) fill(summary *Summary) {
+	summary.ArrivalMode = schedule.mode
+	summary.ArrivalTargetMean = schedule.mean
+	if len(schedule.intervals) == 0 {
+		return
+	}
+
+	var total time.Duration
+	for _, interval := range schedule.intervals {
+		total += interval
+	}
+	summary.ArrivalMean = total / time.Duration(len(schedule.intervals))
+	marks := percentiles(schedule.intervals, 50, 90, 99)
+	summary.ArrivalP50, summary.ArrivalP90, summary.ArrivalP99 = marks[0], marks[1], marks[2]
+}
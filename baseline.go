@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+func loadBaseline(path string) (Summary, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Summary{}, fmt.Errorf("error reading baseline: %v", err)
+	}
+
+	var baseline Summary
+	if err := json.Unmarshal(data, &baseline); err != nil {
+		return Summary{}, fmt.Errorf("error decoding baseline %s: %v", path, err)
+	}
+	if baseline.TotalRequests == 0 {
+		return Summary{}, fmt.Errorf("baseline %s has no requests; expected a -json summary", path)
+	}
+	return baseline, nil
+}
+
+func errorRate(summary Summary) float64 {
+	if summary.TotalRequests == 0 {
+		return 0
+	}
+	return float64(summary.FailureCount) / float64(summary.TotalRequests)
+}
+
+func relativeChange(before float64, after float64) float64 {
+	if before == 0 {
+		return 0
+	}
+	return after/before - 1
+}
+
+// compareBaseline prints how the run moved against the baseline and reports
+// whether any change is past its tolerance.
+func compareBaseline(cfg *Config, baseline Summary, current Summary) bool {
+	rpsChange := relativeChange(baseline.RequestsPerSecond, current.RequestsPerSecond)
+	p99Change := relativeChange(float64(baseline.LatencyP99), float64(current.LatencyP99))
+	errorRateChange := errorRate(current) - errorRate(baseline)
+
+	rpsRegressed := -rpsChange > cfg.MaxRPSDrop
+	p99Regressed := p99Change > cfg.MaxP99Increase
+	errorRateRegressed := errorRateChange > cfg.MaxErrorRateIncrease
+
+	mark := func(regressed bool) string {
+		if regressed {
+			return "  <- регрессия"
+		}
+		return ""
+	}
+
+	fmt.Printf("\n=== Сравнение с базовым запуском ===\n")
+	fmt.Printf("Запросов в секунду: %.2f -> %.2f (%+.1f%%)%s\n",
+		baseline.RequestsPerSecond, current.RequestsPerSecond, rpsChange*100, mark(rpsRegressed))
+	fmt.Printf("p99: %v -> %v (%+.1f%%)%s\n",
+		baseline.LatencyP99, current.LatencyP99, p99Change*100, mark(p99Regressed))
+	fmt.Printf("Доля ошибок: %.2f%% -> %.2f%% (%+.2f п.п.)%s\n",
+		errorRate(baseline)*100, errorRate(current)*100, errorRateChange*100, mark(errorRateRegressed))
+
+	return rpsRegressed || p99Regressed || errorRateRegressed
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// requestBreakdown is where one request's time went, from the trace. Phases
+// a request skipped, like DNS, connect and TLS on a reused connection, stay
+// zero.
+type requestBreakdown struct {
+	poolWait time.Duration
+	dns      time.Duration
+	connect  time.Duration
+	tls      time.Duration
+	send     time.Duration
+	server   time.Duration
+	transfer time.Duration
+}
+
+var breakdownPhases = []string{"pool_wait", "dns", "connect", "tls", "send", "server", "transfer"}
+
+func (breakdown requestBreakdown) durations() []time.Duration {
+	return []time.Duration{breakdown.poolWait, breakdown.dns, breakdown.connect, breakdown.tls,
+		breakdown.send, breakdown.server, breakdown.transfer}
+}
+
+func (breakdown *requestBreakdown) add(other requestBreakdown) {
+	breakdown.poolWait += other.poolWait
+	breakdown.dns += other.dns
+	breakdown.connect += other.connect
+	breakdown.tls += other.tls
+	breakdown.send += other.send
+	breakdown.server += other.server
+	breakdown.transfer += other.transfer
+}
+
+type PhaseShare struct {
+	Phase string `json:"phase"`
+	// Average is over every request in the breakdown, counting skipped
+	// phases as zero, so the averages stack up to the average request.
+	Average  time.Duration `json:"average_ns"`
+	Requests int           `json:"requests"` // requests that went through the phase
+	Share    float64       `json:"share"`
+}
+
+// phaseShares averages the summed breakdown over requests; counts holds how
+// many requests spent any time in each phase.
+func phaseShares(total requestBreakdown, counts []int, requests int) []PhaseShare {
+	durations := total.durations()
+	var sum time.Duration
+	for _, duration := range durations {
+		sum += duration
+	}
+	shares := make([]PhaseShare, len(durations))
+	for i, duration := range durations {
+		shares[i] = PhaseShare{Phase: breakdownPhases[i], Average: duration / time.Duration(requests), Requests: counts[i]}
+		if sum > 0 {
+			shares[i].Share = float64(duration) / float64(sum)
+		}
+	}
+	return shares
+}
+
+const breakdownBarWidth = 40
+
+func printPhaseBreakdown(shares []PhaseShare) {
+	var total time.Duration
+	for _, share := range shares {
+		total += share.Average
+	}
+	fmt.Printf("Из чего складывается средний запрос (%v):\n", total)
+	for _, share := range shares {
+		bar := strings.Repeat("#", int(share.Share*breakdownBarWidth+0.5))
+		fmt.Printf("  %-9s %12v %5.1f%% %-*s (%d запросов)\n", share.Phase, share.Average, share.Share*100, breakdownBarWidth, bar, share.Requests)
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	breakerClosed = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+type circuitBreaker struct {
+	threshold float64
+	cooldown  time.Duration
+
+	mutex    sync.Mutex
+	outcomes []bool
+	next     int
+	filled   int
+	failures int
+	state    int
+	probing  bool
+	openedAt time.Time
+	opens    int
+	openTime time.Duration
+}
+
+func newCircuitBreaker(threshold float64, window int, cooldown time.Duration) *circuitBreaker {
+	return &circuitBreaker{
+		threshold: threshold,
+		cooldown:  cooldown,
+		outcomes:  make([]bool, window),
+	}
+}
+
+// allow blocks while the circuit is open. Once the cooldown passes the circuit goes
+// half-open and lets a single probe request through; its result closes or re-opens it.
+// It returns ctx's error if the run is canceled while waiting.
+func (breaker *circuitBreaker) allow(ctx context.Context) error {
+	for {
+		breaker.mutex.Lock()
+		if breaker.state == breakerClosed {
+			breaker.mutex.Unlock()
+			return nil
+		}
+		if breaker.state == breakerOpen && time.Since(breaker.openedAt) >= breaker.cooldown {
+			breaker.state = breakerHalfOpen
+			breaker.probing = true
+			breaker.mutex.Unlock()
+			fmt.Printf("Circuit breaker half-open, probing server recovery\n")
+			return nil
+		}
+		if breaker.state == breakerHalfOpen && !breaker.probing {
+			breaker.probing = true
+			breaker.mutex.Unlock()
+			return nil
+		}
+		breaker.mutex.Unlock()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(50 * time.Millisecond):
+		}
+	}
+}
+
+// neutral records a result that is neither a success nor a failure. It says
+// nothing about the server's health, so a neutral probe is simply retried.
+func (breaker *circuitBreaker) neutral() {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	if breaker.state == breakerHalfOpen {
+		breaker.probing = false
+	}
+}
+
+func (breaker *circuitBreaker) record(success bool) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+
+	switch breaker.state {
+	case breakerOpen:
+		return
+	case breakerHalfOpen:
+		if success {
+			breaker.openTime += time.Since(breaker.openedAt)
+			breaker.state = breakerClosed
+			breaker.filled, breaker.next, breaker.failures = 0, 0, 0
+			fmt.Printf("Circuit breaker closed after %v\n", time.Since(breaker.openedAt).Round(time.Millisecond))
+		} else {
+			breaker.trip()
+		}
+		return
+	}
+
+	if breaker.filled == len(breaker.outcomes) {
+		if !breaker.outcomes[breaker.next] {
+			breaker.failures--
+		}
+	} else {
+		breaker.filled++
+	}
+	breaker.outcomes[breaker.next] = success
+	breaker.next = (breaker.next + 1) % len(breaker.outcomes)
+	if !success {
+		breaker.failures++
+	}
+
+	if breaker.filled == len(breaker.outcomes) && float64(breaker.failures)/float64(breaker.filled) > breaker.threshold {
+		breaker.trip()
+	}
+}
+
+func (breaker *circuitBreaker) trip() {
+	if breaker.state == breakerHalfOpen {
+		breaker.openTime += time.Since(breaker.openedAt)
+	}
+	breaker.state = breakerOpen
+	breaker.openedAt = time.Now()
+	breaker.opens++
+	fmt.Printf("Circuit breaker open: pausing new requests for %v\n", breaker.cooldown)
+}
+
+func (breaker *circuitBreaker) totals() (int, time.Duration) {
+	breaker.mutex.Lock()
+	defer breaker.mutex.Unlock()
+	openTime := breaker.openTime
+	if breaker.state != breakerClosed {
+		openTime += time.Since(breaker.openedAt)
+	}
+	return breaker.opens, openTime
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// halfOpenBreaker returns a breaker whose single probe has just been let
+// through.
+func halfOpenBreaker(t *testing.T) *circuitBreaker {
+	t.Helper()
+	breaker := newCircuitBreaker(0.5, 2, time.Minute)
+	breaker.record(false)
+	breaker.record(false)
+	if breaker.state != breakerOpen {
+		t.Fatalf("state = %d after two failures, want open", breaker.state)
+	}
+	breaker.openedAt = breaker.openedAt.Add(-time.Minute)
+	if err := breaker.allow(context.Background()); err != nil {
+		t.Fatalf("allow after the cooldown: %v", err)
+	}
+	return breaker
+}
+
+func TestCircuitBreakerProbe(t *testing.T) {
+	tests := []struct {
+		name      string
+		outcome   func(breaker *circuitBreaker)
+		wantState int
+		wantAllow error
+	}{
+		{name: "probe in flight", outcome: func(breaker *circuitBreaker) {}, wantState: breakerHalfOpen, wantAllow: context.DeadlineExceeded},
+		{name: "probe succeeded", outcome: func(breaker *circuitBreaker) { breaker.record(true) }, wantState: breakerClosed},
+		{name: "probe failed", outcome: func(breaker *circuitBreaker) { breaker.record(false) }, wantState: breakerOpen, wantAllow: context.DeadlineExceeded},
+		{name: "probe neutral", outcome: func(breaker *circuitBreaker) { breaker.neutral() }, wantState: breakerHalfOpen},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			breaker := halfOpenBreaker(t)
+			test.outcome(breaker)
+			if breaker.state != test.wantState {
+				t.Errorf("state = %d, want %d", breaker.state, test.wantState)
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			if err := breaker.allow(ctx); !errors.Is(err, test.wantAllow) {
+				t.Errorf("next allow = %v, want %v", err, test.wantAllow)
+			}
+		})
+	}
+}
+
+func TestCircuitBreakerAllowCanceled(t *testing.T) {
+	breaker := newCircuitBreaker(0.5, 1, time.Hour)
+	breaker.record(false)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	done := make(chan error, 1)
+	go func() { done <- breaker.allow(ctx) }()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("allow = %v, want context.Canceled", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("allow kept waiting after the run was canceled")
+	}
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+type BurstStats struct {
+	Index    int           `json:"index"`
+	Requests int           `json:"requests"`
+	Failures int           `json:"failures"`
+	Span     time.Duration `json:"span_ns"`
+	P50      time.Duration `json:"p50_ns"`
+	P99      time.Duration `json:"p99_ns"`
+}
+
+type burstOutcome struct {
+	startedAt  time.Time
+	finishedAt time.Time
+	requests   int
+	failures   int
+	latencies  []time.Duration
+}
+
+// burstSchedule releases requests in groups of size, one group every
+// interval, and keeps outcomes per group to show how the server recovers
+// between spikes.
+type burstSchedule struct {
+	size     int
+	interval time.Duration
+	first    int
+
+	mutex  sync.Mutex
+	bursts []*burstOutcome
+}
+
+func newBurstSchedule(size int, interval time.Duration, first int) *burstSchedule {
+	return &burstSchedule{size: size, interval: interval, first: first}
+}
+
+// wait is called before submitting requestNum and holds back the first
+// request of every burst until the interval since the previous one is over.
+func (schedule *burstSchedule) wait(requestNum int) {
+	offset := requestNum - schedule.first
+	if offset%schedule.size != 0 {
+		return
+	}
+
+	schedule.mutex.Lock()
+	var previous time.Time
+	if len(schedule.bursts) > 0 {
+		previous = schedule.bursts[len(schedule.bursts)-1].startedAt
+	}
+	schedule.mutex.Unlock()
+	if !previous.IsZero() {
+		time.Sleep(time.Until(previous.Add(schedule.interval)))
+	}
+
+	schedule.mutex.Lock()
+	schedule.bursts = append(schedule.bursts, &burstOutcome{startedAt: time.Now()})
+	schedule.mutex.Unlock()
+}
+
+func (schedule *burstSchedule) record(result RequestResult) {
+	index := (result.RequestNum - schedule.first) / schedule.size
+
+	schedule.mutex.Lock()
+	defer schedule.mutex.Unlock()
+	if index < 0 || index >= len(schedule.bursts) {
+		return
+	}
+	burst := schedule.bursts[index]
+	burst.requests++
+	burst.finishedAt = time.Now()
+	if !result.Success && !result.Neutral {
+		burst.failures++
+	}
+	if result.StatusCode > 0 {
+		burst.latencies = append(burst.latencies, result.Duration)
+	}
+}
+
+func (schedule *burstSchedule) fill(summary *Summary) {
+	schedule.mutex.Lock()
+	defer schedule.mutex.Unlock()
+
+	summary.BurstSize = schedule.size
+	summary.BurstInterval = schedule.interval
+	for index, burst := range schedule.bursts {
+		stats := BurstStats{Index: index, Requests: burst.requests, Failures: burst.failures}
+		if !burst.finishedAt.IsZero() {
+			stats.Span = burst.finishedAt.Sub(burst.startedAt)
+		}
+		if len(burst.latencies) > 0 {
+			marks := percentiles(burst.latencies, 50, 99)
+			stats.P50, stats.P99 = marks[0], marks[1]
+		}
+		summary.Bursts = append(summary.Bursts, stats)
+	}
+}
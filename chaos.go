@@ -0,0 +1,57 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	corruptTruncate    = "truncate"
+	corruptContentType = "content-type"
+	corruptOversize    = "oversize"
+)
+
+func parseCorruptions(value string) ([]string, error) {
+	var corruptions []string
+	for _, name := range strings.Split(value, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+			continue
+		case corruptTruncate, corruptContentType, corruptOversize:
+			corruptions = append(corruptions, name)
+		default:
+			return nil, fmt.Errorf("unknown corruption %q (expected truncate, content-type or oversize)", name)
+		}
+	}
+	if len(corruptions) == 0 {
+		return nil, fmt.Errorf("no corruptions given")
+	}
+	return corruptions, nil
+}
+
+// corruptImage returns a malformed copy of image. Oversized uploads share
+// one padded buffer so they don't allocate per request.
+func corruptImage(image ImageFile, corruption string, oversized []byte) ImageFile {
+	switch corruption {
+	case corruptTruncate:
+		image.data = image.data[:len(image.data)/2]
+	case corruptContentType:
+		image.contentType = "text/plain"
+	case corruptOversize:
+		image.data = oversized
+	}
+	return image
+}
+
+// newOversizedPayload starts with a real image header so the upload looks
+// legitimate until the size check.
+func newOversizedPayload(image ImageFile, size int) []byte {
+	payload := make([]byte, max(size, len(image.data)))
+	copy(payload, image.data)
+	return payload
+}
+
+func malformedCategory(statusCode int) string {
+	return "malformed_" + statusCategory(statusCode)
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// checkpointState is what -checkpoint persists. Completed is a watermark:
+// every request below it has finished, whatever its outcome, so a resumed
+// run never repeats an upload that already had its side effects. Requests
+// canceled by an early stop were never sent and hold the watermark back.
+type checkpointState struct {
+	Plan      string    `json:"plan"`
+	Requests  int       `json:"requests"`
+	Completed int       `json:"completed"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type checkpoint struct {
+	path  string
+	state checkpointState
+
+	// Requests finish out of order; finished ones above the watermark wait
+	// here until the gap below them closes.
+	pending map[int]bool
+	mutex   sync.Mutex
+}
+
+func newCheckpoint(path string, plan string, requests int) *checkpoint {
+	return &checkpoint{
+		path:    path,
+		state:   checkpointState{Plan: plan, Requests: requests},
+		pending: make(map[int]bool),
+	}
+}
+
+// resume loads the watermark of an earlier run. A missing, unreadable or
+// mismatched checkpoint starts the run over with a warning rather than
+// failing it.
+func (checkpoint *checkpoint) resume() int {
+	data, err := os.ReadFile(checkpoint.path)
+	if os.IsNotExist(err) {
+		fmt.Printf("Warning: no checkpoint at %s, starting from the beginning\n", checkpoint.path)
+		return 0
+	}
+	if err != nil {
+		fmt.Printf("Warning: couldn't read checkpoint %s, starting from the beginning: %v\n", checkpoint.path, err)
+		return 0
+	}
+
+	var saved checkpointState
+	if err := json.Unmarshal(data, &saved); err != nil || saved.Completed < 0 || saved.Completed > saved.Requests {
+		fmt.Printf("Warning: checkpoint %s is corrupt, starting from the beginning\n", checkpoint.path)
+		return 0
+	}
+	if saved.Plan != checkpoint.state.Plan || saved.Requests != checkpoint.state.Requests {
+		fmt.Printf("Warning: checkpoint %s is for plan %q with %d requests, starting from the beginning\n",
+			checkpoint.path, saved.Plan, saved.Requests)
+		return 0
+	}
+
+	checkpoint.state.Completed = saved.Completed
+	return saved.Completed
+}
+
+func (checkpoint *checkpoint) complete(requestNum int) {
+	checkpoint.mutex.Lock()
+	defer checkpoint.mutex.Unlock()
+
+	checkpoint.pending[requestNum] = true
+	for checkpoint.pending[checkpoint.state.Completed] {
+		delete(checkpoint.pending, checkpoint.state.Completed)
+		checkpoint.state.Completed++
+	}
+}
+
+// save writes through a temporary file so an interrupted write leaves the
+// previous checkpoint intact.
+func (checkpoint *checkpoint) save() error {
+	checkpoint.mutex.Lock()
+	state := checkpoint.state
+	checkpoint.mutex.Unlock()
+
+	state.UpdatedAt = time.Now()
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+	temporary := checkpoint.path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temporary, checkpoint.path)
+}
+
+// startCheckpointWriter saves the checkpoint every interval until the
+// returned function is called, which saves it one last time.
+func startCheckpointWriter(checkpoint *checkpoint, interval time.Duration) func() {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			if err := checkpoint.save(); err != nil {
+				fmt.Printf("Warning: couldn't write checkpoint: %v\n", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+		if err := checkpoint.save(); err != nil {
+			fmt.Printf("Warning: couldn't write checkpoint: %v\n", err)
+		}
+	}
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"hash"
+	"net/http"
+)
+
+const categoryChecksum = "checksum"
+
+// checksumRejectionHints are words servers use when they refuse a body
+// whose checksum header doesn't match, e.g. S3's BadDigest.
+var checksumRejectionHints = [][]byte{[]byte("checksum"), []byte("digest"), []byte("md5"), []byte("sha256"), []byte("integrity")}
+
+func newChecksumHash(algorithm string) hash.Hash {
+	if algorithm == "md5" {
+		return md5.New()
+	}
+	return sha256.New()
+}
+
+func defaultChecksumHeader(algorithm string) string {
+	if algorithm == "md5" {
+		return "Content-MD5"
+	}
+	return "X-Content-SHA256"
+}
+
+// encodeChecksum uses base64 as Content-MD5 (RFC 1864) does.
+func encodeChecksum(digest hash.Hash) string {
+	return base64.StdEncoding.EncodeToString(digest.Sum(nil))
+}
+
+// classifyChecksumRejection files 4xx responses that blame the checksum
+// under their own category.
+func classifyChecksumRejection(statusCode int, _ http.Header, body []byte) (string, bool) {
+	if statusCode < 400 || statusCode >= 500 {
+		return "", false
+	}
+	lower := bytes.ToLower(body)
+	for _, hint := range checksumRejectionHints {
+		if bytes.Contains(lower, hint) {
+			return categoryChecksum, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+func uploadChunks(ctx context.Context, client *http.Client, cfg *Config, job uploadJob, result *RequestResult, bearerToken string) uploadAttempt {
+	image := job.image
+	total := len(image.data)
+	chunkSize := int(cfg.ChunkSize)
+
+	var attempt uploadAttempt
+	for start := 0; start < total; start += chunkSize {
+		end := min(start+chunkSize, total)
+		contentRange := fmt.Sprintf("bytes %d-%d/%d", start, end-1, total)
+
+		chunk := sendUpload(ctx, client, cfg, job, image.data[start:end], contentRange, bearerToken)
+		attempt.statusCode = chunk.statusCode
+		attempt.duration += chunk.duration
+		attempt.bytesSent += chunk.bytesSent
+		attempt.connects += chunk.connects
+		attempt.connWait += chunk.connWait
+		attempt.upload += chunk.upload
+		attempt.server += chunk.server
+		attempt.download += chunk.download
+		attempt.breakdown.add(chunk.breakdown)
+		attempt.body = chunk.body
+		attempt.bodyLength = chunk.bodyLength
+		attempt.header = chunk.header
+		if chunk.protocol != "" {
+			attempt.protocol = chunk.protocol
+		}
+		attempt.localAddr, attempt.remoteAddr = chunk.localAddr, chunk.remoteAddr
+		if chunk.err != nil {
+			attempt.category = chunk.category
+			attempt.err = fmt.Errorf("chunk %s: %v", contentRange, chunk.err)
+			return attempt
+		}
+
+		result.Chunks++
+		result.ChunkTime += chunk.duration
+		if !cfg.successCodes[chunk.statusCode] {
+			break
+		}
+	}
+
+	return attempt
+}
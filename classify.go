@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// failureClassifier maps a failed response to a domain-specific category,
+// e.g. a JSON error code that means the server's queue is full. It reports
+// false when the response is not one it recognises.
+type failureClassifier func(statusCode int, header http.Header, body []byte) (string, bool)
+
+var failureClassifiers []failureClassifier
+
+// registerClassifier adds a classifier consulted for every failed status
+// response; the first one that matches names the category.
+func registerClassifier(classifier failureClassifier) {
+	failureClassifiers = append(failureClassifiers, classifier)
+}
+
+func classifyFailure(statusCode int, header http.Header, body []byte) (string, bool) {
+	for _, classifier := range failureClassifiers {
+		if category, ok := classifier(statusCode, header, body); ok {
+			return category, true
+		}
+	}
+	return "", false
+}
+
+// parseClassifier turns a -classify rule into a classifier. Rules are
+// body:<text>=<category>, header:<name>=<category> and
+// header:<name>:<text>=<category>; the text is matched as a substring and
+// the header name alone matches any value.
+func parseClassifier(rule string) (failureClassifier, error) {
+	index := strings.LastIndex(rule, "=")
+	if index < 0 || index == len(rule)-1 {
+		return nil, fmt.Errorf("expected source:match=category, got %q", rule)
+	}
+	match, category := rule[:index], rule[index+1:]
+
+	source, pattern, ok := strings.Cut(match, ":")
+	if !ok || pattern == "" {
+		return nil, fmt.Errorf("expected source:match=category, got %q", rule)
+	}
+
+	switch source {
+	case "body":
+		text := []byte(pattern)
+		return func(_ int, _ http.Header, body []byte) (string, bool) {
+			return category, bytes.Contains(body, text)
+		}, nil
+	case "header":
+		name, text, _ := strings.Cut(pattern, ":")
+		return func(_ int, header http.Header, _ []byte) (string, bool) {
+			values, present := header[http.CanonicalHeaderKey(name)]
+			if !present {
+				return "", false
+			}
+			if text == "" {
+				return category, true
+			}
+			for _, value := range values {
+				if strings.Contains(value, text) {
+					return category, true
+				}
+			}
+			return "", false
+		}, nil
+	default:
+		return nil, fmt.Errorf("unknown source %q in %q: expected body or header", source, rule)
+	}
+}
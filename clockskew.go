@@ -0,0 +1,39 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+var clockSkewWarning sync.Once
+
+func checkClockSkew(header http.Header, received time.Time, threshold time.Duration) {
+	if threshold <= 0 {
+		return
+	}
+
+	dateHeader := header.Get("Date")
+	if dateHeader == "" {
+		return
+	}
+
+	serverTime, err := http.ParseTime(dateHeader)
+	if err != nil {
+		return
+	}
+
+	// Date only has one-second resolution, so the threshold should stay well above that.
+	skew := received.Sub(serverTime)
+	if skew < 0 {
+		skew = -skew
+	}
+
+	if skew > threshold {
+		clockSkewWarning.Do(func() {
+			fmt.Printf("Warning: server clock differs from local clock by %v (Date: %s); server-side timings may not be comparable\n",
+				skew.Round(time.Second), dateHeader)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+const concurrencySampleInterval = 50 * time.Millisecond
+
+type concurrencyStats struct {
+	configured int
+	average    float64
+	max        int
+}
+
+// startConcurrencySampler samples how many requests are actually on the
+// wire, which the per-worker pause and slow responses keep below the pool
+// size. configured is averaged too, since signals can resize the pool.
+func startConcurrencySampler(active *atomic.Int64, pool *workerPool) func() concurrencyStats {
+	done := make(chan struct{})
+	result := make(chan concurrencyStats, 1)
+	go func() {
+		ticker := time.NewTicker(concurrencySampleInterval)
+		defer ticker.Stop()
+
+		var stats concurrencyStats
+		samples, total, configured := 0, 0, 0
+		for {
+			select {
+			case <-done:
+				if samples > 0 {
+					stats.average = float64(total) / float64(samples)
+					stats.configured = (configured + samples/2) / samples
+				} else {
+					stats.configured = pool.currentSize()
+				}
+				result <- stats
+				return
+			case <-ticker.C:
+			}
+
+			current := int(active.Load())
+			samples++
+			total += current
+			configured += pool.currentSize()
+			stats.max = max(stats.max, current)
+		}
+	}()
+
+	return func() concurrencyStats {
+		close(done)
+		return <-result
+	}
+}
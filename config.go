@@ -0,0 +1,1032 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var version = "dev"
+
+const browserUserAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/131.0.0.0 Safari/537.36"
+
+type Config struct {
+	URL         string `toml:"url"`
+	Interactive bool   `toml:"interactive"`
+	Yes         bool   `toml:"yes"`
+	Mode        string `toml:"mode"`
+	GRPCMethod  string `toml:"grpc-method"`
+	Requests    int    `toml:"requests"`
+	Concurrency int    `toml:"concurrency"`
+	Queue       int    `toml:"queue"`
+	RaiseUlimit bool   `toml:"raise-ulimit"`
+
+	Arrival     string        `toml:"arrival"`
+	ArrivalMean time.Duration `toml:"arrival-mean"`
+
+	Burst         int           `toml:"burst"`
+	BurstInterval time.Duration `toml:"burst-interval"`
+
+	MaxConnsPerHost int        `toml:"max-conns-per-host"`
+	Prewarm         bool       `toml:"prewarm"`
+	MaxPerHost      int        `toml:"max-per-host"`
+	HTTPVersion     string     `toml:"http-version"`
+	Resolve         stringList `toml:"resolve"`
+	SourcePorts     string     `toml:"source-ports"`
+	ReuseAddr       bool       `toml:"reuseaddr"`
+
+	Folder    stringList `toml:"folder"`
+	File      string     `toml:"file"`
+	Stdin     bool       `toml:"stdin"`
+	StdinName string     `toml:"stdin-name"`
+	StdinType string     `toml:"stdin-type"`
+	Plan      string     `toml:"plan"`
+
+	Synthetic       ByteSize `toml:"synthetic"`
+	SyntheticFormat string   `toml:"synthetic-format"`
+	SyntheticDim    string   `toml:"synthetic-dim"`
+	ProbeMaxSize    bool     `toml:"probe-max-size"`
+	ProbeLimit      ByteSize `toml:"probe-limit"`
+
+	Checkpoint         string        `toml:"checkpoint"`
+	CheckpointInterval time.Duration `toml:"checkpoint-interval"`
+	ReportInterval     time.Duration `toml:"report-interval"`
+	Resume             bool          `toml:"resume"`
+
+	TargetErrors  float64  `toml:"target-errors"`
+	Corruptions   string   `toml:"corruptions"`
+	RejectCodes   string   `toml:"reject-codes"`
+	OversizeBytes ByteSize `toml:"oversize-size"`
+
+	ValidateImages  bool   `toml:"validate-images"`
+	Dedupe          bool   `toml:"dedupe"`
+	PartitionImages bool   `toml:"partition-images"`
+	SizeWeighted    string `toml:"size-weighted"`
+	Seed            uint64 `toml:"seed"`
+
+	SuccessCodes string     `toml:"success-codes"`
+	Classify     stringList `toml:"classify"`
+
+	Checksum       string `toml:"checksum"`
+	ChecksumHeader string `toml:"checksum-header"`
+	ChecksumScope  string `toml:"checksum-scope"`
+
+	JSONOutput        string `toml:"json"`
+	ReportFile        string `toml:"report-file"`
+	FormatCompat      string `toml:"format-compat"`
+	CSVOutput         string `toml:"csv"`
+	OpenMetricsOutput string `toml:"openmetrics"`
+	EventsOutput      string `toml:"events"`
+
+	FailuresFile string `toml:"failures-file"`
+	SQLiteOutput string `toml:"sqlite"`
+
+	SLO stringList `toml:"slo"`
+
+	ErrorPath string `toml:"error-path"`
+	ErrorTop  int    `toml:"error-top"`
+
+	Baseline             string  `toml:"baseline"`
+	MaxRPSDrop           float64 `toml:"max-rps-drop"`
+	MaxP99Increase       float64 `toml:"max-p99-increase"`
+	MaxErrorRateIncrease float64 `toml:"max-error-rate-increase"`
+
+	SQLiteRequests bool `toml:"sqlite-requests"`
+	StopOnError    bool `toml:"stop-on-error"`
+
+	Retries      int           `toml:"retries"`
+	RetryBackoff time.Duration `toml:"retry-backoff"`
+	RetryBudget  float64       `toml:"retry-budget"`
+
+	CloseRate      float64 `toml:"close-rate"`
+	PhaseBreakdown bool    `toml:"phase-breakdown"`
+	DumpHeaders    float64 `toml:"dump-headers"`
+	DumpSecrets    bool    `toml:"dump-secrets"`
+
+	Cooldown           time.Duration `toml:"cooldown"`
+	MemoryInterval     time.Duration `toml:"memory-interval"`
+	AssertMemoryStable bool          `toml:"assert-memory-stable"`
+	MemoryTolerance    float64       `toml:"memory-tolerance"`
+
+	StallWindow   time.Duration `toml:"stall-window"`
+	Watchdog      time.Duration `toml:"watchdog"`
+	WatchdogAbort bool          `toml:"watchdog-abort"`
+	IdleTimeout   time.Duration `toml:"idle-timeout"`
+	MaxRuntime    time.Duration `toml:"max-runtime"`
+	Forever       bool          `toml:"forever"`
+
+	ConcurrencySweep string        `toml:"concurrency-sweep"`
+	SweepStep        time.Duration `toml:"sweep-step"`
+	SweepCSV         string        `toml:"sweep-csv"`
+
+	BreakerThreshold float64       `toml:"breaker-threshold"`
+	BreakerWindow    int           `toml:"breaker-window"`
+	BreakerCooldown  time.Duration `toml:"breaker-cooldown"`
+
+	Slowest int `toml:"slowest"`
+
+	TUI   bool   `toml:"tui"`
+	Pprof string `toml:"pprof"`
+
+	SoakInterval time.Duration `toml:"soak-interval"`
+	SoakFile     string        `toml:"soak-file"`
+
+	ChunkSize ByteSize `toml:"chunk-size"`
+
+	ClockSkewThreshold time.Duration `toml:"clock-skew-threshold"`
+
+	Format        string   `toml:"format"`
+	MaxBodyBuffer ByteSize `toml:"max-body-buffer"`
+	Chunked       bool     `toml:"chunked"`
+	BatchSize     int      `toml:"batch"`
+
+	BodyTemplate string     `toml:"body-template"`
+	URLTemplate  string     `toml:"url-template"`
+	FormFields   stringList `toml:"form"`
+	Boundary     string     `toml:"boundary"`
+	FileField    string     `toml:"file-field"`
+	Parts        stringList `toml:"part"`
+
+	UserAgent      string `toml:"user-agent"`
+	BrowserHeaders bool   `toml:"browser-headers"`
+
+	Conditional string `toml:"conditional"`
+	TraceHeader string `toml:"trace-header"`
+
+	LoginURL  string     `toml:"login-url"`
+	LoginForm stringList `toml:"login-form"`
+
+	OAuthTokenURL string `toml:"oauth-token-url"`
+	ClientID      string `toml:"client-id"`
+	ClientSecret  string `toml:"client-secret"`
+	Scope         string `toml:"scope"`
+
+	Labels stringList `toml:"label"`
+
+	VerifyGet    string  `toml:"verify-get"`
+	VerifySample float64 `toml:"verify-sample"`
+	ExpectLength string  `toml:"expect-length"`
+
+	ExpectContentType string `toml:"expect-content-type"`
+
+	Transform        string `toml:"transform"`
+	TransformKey     string `toml:"transform-key"`
+	TransformKeyFile string `toml:"transform-key-file"`
+
+	configHash   string
+	successCodes map[int]bool
+	rejectCodes  map[int]bool
+	corruptions  []string
+	formFields   []formField
+	loginFields  []formField
+	labels       map[string]string
+	folders      []imageFolder
+	slos         []sloTarget
+	errorPath    jsonPath
+	sweepLevels  []int
+	parts        []namedPart
+	transforms   []string
+	transformKey []byte
+	encryption   string
+	resolves     map[string]string
+	sourcePorts  *portRange
+	prewarmConns int
+	expectLength *lengthRange
+	contentTypes map[string]bool
+
+	syntheticWidth  int
+	syntheticHeight int
+}
+
+type namedPart struct {
+	field string
+	image ImageFile
+}
+
+type formField struct {
+	key   string
+	value string
+}
+
+func registerFlags(cfg *Config) {
+	flag.StringVar(&cfg.URL, "url", "http://axxonnet.test/api/v1/faceLists/1/faces/bulk", "upload endpoint")
+	flag.BoolVar(&cfg.Interactive, "interactive", false, "show the target and planned load and ask for confirmation before starting")
+	flag.BoolVar(&cfg.Yes, "yes", false, "skip the confirmation asked with -interactive or for production-looking hosts")
+	flag.StringVar(&cfg.Mode, "mode", "http", "transport: http (multipart POST), ws (one binary WebSocket frame per image, any reply is the ack) or grpc (a client stream per image, needs a build with -tags grpc)")
+	flag.StringVar(&cfg.GRPCMethod, "grpc-method", "/uploader.v1.ImageUploader/Upload", "full name of the client-streaming method -mode grpc calls")
+	flag.IntVar(&cfg.Requests, "requests", 1000, "total number of requests to send (default from $TOTAL_REQUESTS if set)")
+	flag.IntVar(&cfg.Concurrency, "concurrency", 10, "maximum number of requests in flight (default from $CONCURRENCY if set)")
+	flag.IntVar(&cfg.Queue, "queue", 0, "how many requests the producer may queue ahead of the workers")
+	flag.BoolVar(&cfg.RaiseUlimit, "raise-ulimit", false, "try to raise the open file limit when it is too low for -concurrency")
+	flag.IntVar(&cfg.MaxPerHost, "max-per-host", 0, "limit requests in flight to each target host, within -concurrency (0 means unlimited)")
+	flag.BoolVar(&cfg.Prewarm, "prewarm", false, "open one idle connection per worker with HEAD requests before the measured run starts")
+	flag.IntVar(&cfg.MaxConnsPerHost, "max-conns-per-host", 0, "limit connections per host in the HTTP transport (0 means unlimited)")
+	flag.StringVar(&cfg.HTTPVersion, "http-version", "", "force the HTTP protocol: 1.1 or 2 (default negotiates)")
+	flag.StringVar(&cfg.SourcePorts, "source-ports", "", "bind connections to local ports from this start:end range, in turn")
+	flag.BoolVar(&cfg.ReuseAddr, "reuseaddr", false, "set SO_REUSEADDR on connections so source ports in TIME_WAIT can be bound again")
+	flag.Var(&cfg.Resolve, "resolve", "connect to host:port at this address instead of resolving it, as host:port:ip, repeatable")
+	flag.Var(&cfg.Folder, "folder", "folder with images to upload (default 1); repeat as path=weight to mix several folders in proportion")
+	flag.StringVar(&cfg.File, "file", "", "single image file to upload repeatedly")
+	flag.BoolVar(&cfg.Stdin, "stdin", false, "read a single payload from stdin")
+	flag.StringVar(&cfg.StdinName, "stdin-name", "stdin.jpg", "filename to send for the stdin payload")
+	flag.StringVar(&cfg.StdinType, "stdin-type", "image/jpeg", "MIME type to send for the stdin payload")
+	flag.StringVar(&cfg.Checkpoint, "checkpoint", "", "persist how many requests have finished to this file, so -resume can continue an interrupted run")
+	flag.DurationVar(&cfg.CheckpointInterval, "checkpoint-interval", 5*time.Second, "how often to write -checkpoint")
+	flag.BoolVar(&cfg.Resume, "resume", false, "skip the requests an earlier run recorded as finished in -checkpoint")
+	flag.Var(&cfg.Synthetic, "synthetic", "upload a generated noise image of roughly this size (e.g. 500KB) instead of reading files")
+	flag.StringVar(&cfg.SyntheticFormat, "synthetic-format", "jpeg", "encoding of the -synthetic image: jpeg or png")
+	flag.BoolVar(&cfg.ProbeMaxSize, "probe-max-size", false, "instead of a load test, binary-search the largest synthetic upload the server accepts")
+	cfg.ProbeLimit = 256 << 20
+	flag.Var(&cfg.ProbeLimit, "probe-limit", "largest payload -probe-max-size tries")
+	flag.StringVar(&cfg.SyntheticDim, "synthetic-dim", "", "fixed WxH dimensions of the synthetic image; the size follows from them")
+	flag.StringVar(&cfg.SuccessCodes, "success-codes", "200", "comma-separated HTTP status codes counted as success")
+	flag.StringVar(&cfg.JSONOutput, "json", "", "write the run summary as JSON to this file")
+	flag.StringVar(&cfg.ReportFile, "report-file", "", "keep a JSON summary of the run so far in this file, replaced atomically every -report-interval and with the final summary at the end")
+	flag.DurationVar(&cfg.ReportInterval, "report-interval", 5*time.Second, "how often to replace -report-file")
+	flag.StringVar(&cfg.FormatCompat, "format-compat", "", "write -json in another tool's summary schema instead of ours: k6")
+	flag.StringVar(&cfg.CSVOutput, "csv", "", "write one CSV row per request to this file")
+	flag.DurationVar(&cfg.Cooldown, "cooldown", 10*time.Second, "wait this long after the run and measure memory again (0 disables)")
+	flag.BoolVar(&cfg.AssertMemoryStable, "assert-memory-stable", false, "exit with status 5 unless container memory after -cooldown is back within -memory-tolerance of the initial usage")
+	flag.Float64Var(&cfg.MemoryTolerance, "memory-tolerance", 0.1, "allowed growth of settled memory over the initial usage, as a fraction of it, for -assert-memory-stable")
+	flag.Var(&cfg.ChunkSize, "chunk-size", "split files larger than this (e.g. 8MB) into Content-Range chunks")
+	flag.DurationVar(&cfg.ClockSkewThreshold, "clock-skew-threshold", 5*time.Second, "warn once if the server Date header differs from local time by more than this (0 disables)")
+	flag.StringVar(&cfg.LoginURL, "login-url", "", "POST -login-form here before the run and send the session cookies it sets with every upload")
+	flag.StringVar(&cfg.OAuthTokenURL, "oauth-token-url", "", "fetch an OAuth2 client-credentials token here before the run and send it instead of the static bearer token, refreshing it as it expires (-mode http only)")
+	flag.StringVar(&cfg.ClientID, "client-id", "", "OAuth2 client ID for -oauth-token-url")
+	flag.StringVar(&cfg.ClientSecret, "client-secret", "", "OAuth2 client secret for -oauth-token-url")
+	flag.StringVar(&cfg.Scope, "scope", "", "OAuth2 scopes to request, separated by spaces or commas")
+	flag.Var(&cfg.Labels, "label", "tag the run with key=value in every output, repeatable (e.g. env=staging)")
+	flag.Var(&cfg.LoginForm, "login-form", "login form field as key=value, repeatable; $VAR is expanded and @path reads the value from a file")
+	flag.Var(&cfg.FormFields, "form", "extra multipart field as key=value, repeatable; $VAR is expanded and @path reads the value from a file")
+	flag.StringVar(&cfg.FailuresFile, "failures-file", "", "append one tab-separated line per failed request (number, image, category, message, trace ID)")
+	flag.BoolVar(&cfg.Dedupe, "dedupe", false, "keep one copy in memory of images with identical bytes when loading folders; every name is still sent")
+	flag.BoolVar(&cfg.ValidateImages, "validate-images", false, "decode every loaded image before the run and drop the ones that fail")
+	flag.BoolVar(&cfg.PartitionImages, "partition-images", false, "give each worker its own disjoint subset of images instead of cycling through all")
+	flag.StringVar(&cfg.UserAgent, "user-agent", "uploader_test/"+version, "User-Agent header to send")
+	flag.BoolVar(&cfg.BrowserHeaders, "browser-headers", false, "send Chrome-like Origin/Referer/Sec-Fetch headers (and a Chrome User-Agent unless -user-agent is set)")
+	flag.StringVar(&cfg.OpenMetricsOutput, "openmetrics", "", "write final metrics in OpenMetrics text format to this file")
+	flag.StringVar(&cfg.Conditional, "conditional", "", "send an image-hash ETag as if-none-match or if-match; 304 responses are counted separately")
+	flag.DurationVar(&cfg.SoakInterval, "soak-interval", 0, "print an interim summary at this interval while the run continues (0 disables)")
+	flag.StringVar(&cfg.SoakFile, "soak-file", "", "append interim soak summaries to this file instead of stdout")
+	flag.StringVar(&cfg.Plan, "plan", "", "CSV file describing each request (image, list_id, extra form field columns); rows loop if fewer than -requests")
+	flag.DurationVar(&cfg.StallWindow, "stall-window", 10*time.Second, "warn when all workers are busy with no completions for this long (0 disables)")
+	flag.StringVar(&cfg.EventsOutput, "events", "", "stream one JSON object per completed request to this file (- for stdout)")
+	flag.StringVar(&cfg.FileField, "file-field", "file[]", "multipart field name for the uploaded image")
+	flag.Var(&cfg.Parts, "part", "additional file part as field=path attached to every request, repeatable")
+	flag.Float64Var(&cfg.BreakerThreshold, "breaker-threshold", 0, "open the circuit when the failure rate over -breaker-window exceeds this fraction (0 disables)")
+	flag.IntVar(&cfg.BreakerWindow, "breaker-window", 50, "number of recent requests the circuit breaker looks at")
+	flag.DurationVar(&cfg.BreakerCooldown, "breaker-cooldown", 10*time.Second, "how long the circuit stays open before probing")
+	flag.StringVar(&cfg.Transform, "transform", "", "comma-separated transforms applied to image bytes in order: gzip, aes")
+	flag.StringVar(&cfg.TransformKey, "transform-key", "", "hex-encoded AES key for the aes transform")
+	flag.StringVar(&cfg.TransformKeyFile, "transform-key-file", "", "file with the raw AES key for the aes transform")
+	flag.IntVar(&cfg.Slowest, "slowest", 10, "report this many slowest requests (0 disables)")
+	flag.StringVar(&cfg.TraceHeader, "trace-header", "", "send a unique correlation ID per request in this header (e.g. X-Request-Id)")
+	flag.StringVar(&cfg.SizeWeighted, "size-weighted", "", "pick images at random weighted by file size: direct or inverse")
+	flag.Uint64Var(&cfg.Seed, "seed", 0, "seed for random choices (0 picks one from the clock)")
+	flag.StringVar(&cfg.VerifyGet, "verify-get", "", "after a successful upload GET this URL, with {id} replaced by the id from the response, and expect 200")
+	flag.Float64Var(&cfg.VerifySample, "verify-sample", 1, "fraction of successful uploads to verify with -verify-get")
+	flag.StringVar(&cfg.ExpectContentType, "expect-content-type", "", "fail successful responses whose Content-Type is not one of these comma-separated media types, e.g. application/json; charset and other parameters are ignored")
+	flag.StringVar(&cfg.ExpectLength, "expect-length", "", "fail successful responses whose body length is outside min:max bytes (either side may be empty; a single number means exact)")
+	flag.StringVar(&cfg.Format, "format", "multipart", "request body format: multipart or ndjson (base64 images, one JSON object per line)")
+	flag.IntVar(&cfg.BatchSize, "batch", 1, "number of images sent in each request")
+	flag.IntVar(&cfg.Retries, "retries", 0, "resend connection errors, timeouts, 429 and 502-504 up to this many times")
+	flag.DurationVar(&cfg.RetryBackoff, "retry-backoff", 200*time.Millisecond, "pause before the first retry, doubled for each further one")
+	flag.Float64Var(&cfg.RetryBudget, "retry-budget", 0, "allow retries for at most this fraction of requests, so an outage can't multiply the load (0 means no budget)")
+	flag.BoolVar(&cfg.PhaseBreakdown, "phase-breakdown", false, "show how the average request splits into pool wait, DNS, connect, TLS, send, server and transfer time")
+	flag.Float64Var(&cfg.CloseRate, "close-rate", 0, "fraction of requests sent with Connection: close, so their connection is torn down after the response")
+	flag.Float64Var(&cfg.DumpHeaders, "dump-headers", 0, "fraction of requests whose request and response headers are printed (0 disables)")
+	flag.BoolVar(&cfg.DumpSecrets, "dump-secrets", false, "print Authorization and cookie headers in full with -dump-headers instead of redacting them")
+	flag.BoolVar(&cfg.StopOnError, "stop-on-error", false, "stop the run on the first failure that isn't a connection error, 429 or 502-504, and exit non-zero")
+	flag.Float64Var(&cfg.TargetErrors, "target-errors", 0, "fraction of requests sent malformed on purpose; they pass only if rejected with -reject-codes")
+	flag.StringVar(&cfg.Corruptions, "corruptions", "truncate,content-type,oversize", "comma-separated corruptions for -target-errors: truncate, content-type, oversize")
+	flag.StringVar(&cfg.RejectCodes, "reject-codes", "400,413,415,422", "status codes that count as a correct rejection of a malformed upload")
+	cfg.OversizeBytes = 64 << 20
+	flag.Var(&cfg.OversizeBytes, "oversize-size", "size of the oversize corruption payload")
+	flag.DurationVar(&cfg.Watchdog, "watchdog", 0, "dump goroutine stacks when requests are in flight but none completes for this long (0 disables)")
+	flag.BoolVar(&cfg.WatchdogAbort, "watchdog-abort", false, "exit with status 2 after the -watchdog dump")
+	flag.BoolVar(&cfg.Forever, "forever", false, "ignore -requests and send until SIGINT/SIGTERM, printing stats for the last 10s every 5s")
+	flag.StringVar(&cfg.ConcurrencySweep, "concurrency-sweep", "", "run at each of these increasing concurrency levels for -sweep-step, e.g. 1,2,5,10,20,50, and report RPS and p99 per level; replaces -requests and -concurrency")
+	flag.DurationVar(&cfg.SweepStep, "sweep-step", 10*time.Second, "how long each -concurrency-sweep level runs")
+	flag.StringVar(&cfg.SweepCSV, "sweep-csv", "", "write the -concurrency-sweep table to this CSV file")
+	flag.DurationVar(&cfg.MaxRuntime, "max-runtime", 0, "hard stop: cancel the run after this long whatever its progress, report what completed and exit with status 6 (0 disables)")
+	flag.DurationVar(&cfg.IdleTimeout, "idle-timeout", 0, "abort the run with exit status 3 and a partial summary if no request completes for this long (0 disables)")
+	flag.StringVar(&cfg.SQLiteOutput, "sqlite", "", "append a row for this run to the runs table of this SQLite database")
+	flag.BoolVar(&cfg.SQLiteRequests, "sqlite-requests", false, "also store one row per request in the requests table of -sqlite")
+	flag.StringVar(&cfg.URLTemplate, "url-template", "", "text/template rendered per request into the upload URL instead of -url, with the same data and helpers as -body-template")
+	flag.StringVar(&cfg.BodyTemplate, "body-template", "", "text/template (or @path) rendered per request into extra form fields: key=value lines or a JSON object; has .Index, .ImageName, .TraceID, randInt min max and uuid")
+	flag.StringVar(&cfg.ErrorPath, "error-path", "", "JSON path such as $.error.message to read from failed response bodies; the top -error-top messages are reported")
+	flag.IntVar(&cfg.ErrorTop, "error-top", 5, "how many distinct -error-path messages to report")
+	flag.Var(&cfg.SLO, "slo", "report the share of requests within this latency, repeatable; 200ms:99 also exits with status 7 unless 99% are within it")
+	flag.StringVar(&cfg.Baseline, "baseline", "", "compare the run against this -json summary and exit with status 4 on a regression")
+	flag.Float64Var(&cfg.MaxRPSDrop, "max-rps-drop", 0.1, "largest tolerated drop in requests per second against -baseline, as a fraction")
+	flag.Float64Var(&cfg.MaxP99Increase, "max-p99-increase", 0.2, "largest tolerated increase in p99 latency against -baseline, as a fraction")
+	flag.Float64Var(&cfg.MaxErrorRateIncrease, "max-error-rate-increase", 0.01, "largest tolerated increase in the failure rate against -baseline, as a fraction of requests")
+	flag.BoolVar(&cfg.TUI, "tui", false, "show a live dashboard instead of scrolling output (plain output when stdout is not a terminal)")
+	flag.StringVar(&cfg.Boundary, "boundary", "", "fixed multipart boundary instead of a random one, for byte-for-byte comparisons")
+	flag.StringVar(&cfg.Pprof, "pprof", "", "serve net/http/pprof on this loopback address during the run, e.g. :6060")
+	flag.StringVar(&cfg.Arrival, "arrival", "", "draw gaps between requests from a distribution instead of the fixed per-worker pause: poisson or uniform")
+	flag.DurationVar(&cfg.ArrivalMean, "arrival-mean", 100*time.Millisecond, "mean gap between requests for -arrival")
+	flag.IntVar(&cfg.Burst, "burst", 0, "release requests in bursts of this many as fast as the workers allow, then idle until -burst-interval (0 disables)")
+	flag.DurationVar(&cfg.BurstInterval, "burst-interval", 10*time.Second, "time from the start of one -burst to the next")
+	flag.StringVar(&cfg.Checksum, "checksum", "", "attach a base64 checksum header to every upload: md5 or sha256")
+	flag.StringVar(&cfg.ChecksumHeader, "checksum-header", "", "header for -checksum (default Content-MD5 for md5, X-Content-SHA256 for sha256)")
+	flag.StringVar(&cfg.ChecksumScope, "checksum-scope", "file", "what -checksum covers: file (the image bytes) or body (the whole request body)")
+	flag.Var(&cfg.Classify, "classify", "custom failure category as body:text=category, header:name=category or header:name:text=category, repeatable")
+	cfg.MaxBodyBuffer = 1 << 20
+	flag.Var(&cfg.MaxBodyBuffer, "max-body-buffer", "build multipart bodies up to this size in memory and stream larger ones")
+	flag.BoolVar(&cfg.Chunked, "chunked", false, "send bodies with Transfer-Encoding: chunked instead of a Content-Length (HTTP/1.1 only)")
+	flag.DurationVar(&cfg.MemoryInterval, "memory-interval", 0, "sample container memory at this interval during the run and report percentiles (0 disables)")
+}
+
+func parseConfig() (*Config, error) {
+	cfg := &Config{}
+	registerFlags(cfg)
+	if err := applyEnvDefaults(cfg); err != nil {
+		return nil, err
+	}
+
+	var configFile string
+	flag.StringVar(&configFile, "config", "", "TOML config file with flag values; command-line flags take precedence")
+
+	fileKeys := map[string]bool{}
+	if path := findConfigFlag(os.Args[1:]); path != "" {
+		keys, err := loadConfigFile(path, cfg)
+		if err != nil {
+			return nil, err
+		}
+		fileKeys = keys
+	}
+
+	flag.Parse()
+	cfg.configHash = hashFlags()
+
+	setFlags := map[string]bool{}
+	for key := range fileKeys {
+		setFlags[key] = true
+	}
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+
+	if cfg.BrowserHeaders && !setFlags["user-agent"] {
+		cfg.UserAgent = browserUserAgent
+	}
+
+	sources := 0
+	if setFlags["folder"] {
+		sources++
+	}
+	if cfg.File != "" {
+		sources++
+	}
+	if cfg.Stdin {
+		sources++
+	}
+	if cfg.Plan != "" {
+		sources++
+	}
+	if cfg.Synthetic > 0 || cfg.SyntheticDim != "" {
+		sources++
+	}
+	if sources > 1 {
+		return nil, fmt.Errorf("only one of -folder, -file, -stdin, -plan or -synthetic can be specified")
+	}
+	switch cfg.FormatCompat {
+	case "":
+	case "k6":
+		if cfg.JSONOutput == "" {
+			return nil, fmt.Errorf("-format-compat needs a -json file to write")
+		}
+	default:
+		return nil, fmt.Errorf("invalid -format-compat %q: expected k6", cfg.FormatCompat)
+	}
+
+	if cfg.DumpHeaders < 0 || cfg.DumpHeaders > 1 {
+		return nil, fmt.Errorf("-dump-headers must be between 0 and 1")
+	}
+	if cfg.CloseRate < 0 || cfg.CloseRate > 1 {
+		return nil, fmt.Errorf("-close-rate must be between 0 and 1")
+	}
+
+	if cfg.Retries < 0 || cfg.RetryBudget < 0 {
+		return nil, fmt.Errorf("-retries and -retry-budget must not be negative")
+	}
+
+	if cfg.Resume && cfg.Checkpoint == "" {
+		return nil, fmt.Errorf("-resume needs a -checkpoint file")
+	}
+	if cfg.ReportFile != "" && cfg.ReportInterval <= 0 {
+		return nil, fmt.Errorf("-report-interval must be positive")
+	}
+	if cfg.Checkpoint != "" && cfg.CheckpointInterval <= 0 {
+		return nil, fmt.Errorf("-checkpoint-interval must be positive")
+	}
+	if cfg.ConcurrencySweep != "" {
+		levels, err := parseSweepLevels(cfg.ConcurrencySweep)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -concurrency-sweep: %v", err)
+		}
+		cfg.sweepLevels = levels
+		if cfg.SweepStep <= 0 {
+			return nil, fmt.Errorf("-sweep-step must be positive")
+		}
+		// -max-runtime is a safety net; one inside the planned sweep would
+		// silently drop its last levels.
+		if planned := time.Duration(len(levels)) * cfg.SweepStep; cfg.MaxRuntime > 0 && cfg.MaxRuntime <= planned {
+			return nil, fmt.Errorf("-max-runtime %v must be longer than the %v the -concurrency-sweep is planned to take", cfg.MaxRuntime, planned)
+		}
+		if cfg.Forever {
+			return nil, fmt.Errorf("-concurrency-sweep cannot be combined with -forever")
+		}
+	}
+	if cfg.SweepCSV != "" && cfg.ConcurrencySweep == "" {
+		return nil, fmt.Errorf("-sweep-csv needs -concurrency-sweep")
+	}
+
+	if cfg.Prewarm {
+		if cfg.Mode != "http" {
+			return nil, fmt.Errorf("-prewarm only works with -mode http")
+		}
+		cfg.prewarmConns = cfg.Concurrency
+		if len(cfg.sweepLevels) > 0 {
+			cfg.prewarmConns = cfg.sweepLevels[len(cfg.sweepLevels)-1]
+		}
+		if cfg.MaxConnsPerHost > 0 {
+			cfg.prewarmConns = min(cfg.prewarmConns, cfg.MaxConnsPerHost)
+		}
+	}
+
+	// The watermark is relative to a fixed request count.
+	if (cfg.Forever || cfg.ConcurrencySweep != "") && cfg.Checkpoint != "" {
+		return nil, fmt.Errorf("-forever and -concurrency-sweep cannot be combined with -checkpoint")
+	}
+	if cfg.ProbeMaxSize && (cfg.ProbeLimit <= probeBaseSize || cfg.Mode != "http") {
+		return nil, fmt.Errorf("-probe-max-size needs -mode http and a -probe-limit above %d bytes", probeBaseSize)
+	}
+	// A probe is not a load run and finishes no reporters.
+	if cfg.ProbeMaxSize {
+		outputs := []struct {
+			flag string
+			set  bool
+		}{
+			{"-json", cfg.JSONOutput != ""},
+			{"-report-file", cfg.ReportFile != ""},
+			{"-csv", cfg.CSVOutput != ""},
+			{"-sweep-csv", cfg.SweepCSV != ""},
+			{"-openmetrics", cfg.OpenMetricsOutput != ""},
+			{"-events", cfg.EventsOutput != ""},
+			{"-failures-file", cfg.FailuresFile != ""},
+			{"-sqlite", cfg.SQLiteOutput != ""},
+		}
+		for _, output := range outputs {
+			if output.set {
+				return nil, fmt.Errorf("-probe-max-size writes no reports and cannot be combined with %s", output.flag)
+			}
+		}
+	}
+	if cfg.SyntheticFormat != "jpeg" && cfg.SyntheticFormat != "png" {
+		return nil, fmt.Errorf("invalid -synthetic-format %q: expected jpeg or png", cfg.SyntheticFormat)
+	}
+	if cfg.SyntheticDim != "" {
+		width, height, err := parseDimensions(cfg.SyntheticDim)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -synthetic-dim: %v", err)
+		}
+		cfg.syntheticWidth, cfg.syntheticHeight = width, height
+	}
+
+	switch cfg.Mode {
+	case "http":
+	case "ws", "grpc":
+		if cfg.BatchSize > 1 || cfg.Format != "multipart" || cfg.ChunkSize > 0 || cfg.VerifyGet != "" {
+			return nil, fmt.Errorf("-mode %s sends one image per call and cannot be combined with -batch, -format, -chunk-size or -verify-get", cfg.Mode)
+		}
+		if cfg.Mode == "grpc" && !grpcSupported {
+			return nil, fmt.Errorf("-mode grpc needs a build with -tags grpc")
+		}
+		if cfg.Mode == "grpc" && !strings.HasPrefix(cfg.GRPCMethod, "/") {
+			return nil, fmt.Errorf("invalid -grpc-method %q: expected /package.Service/Method", cfg.GRPCMethod)
+		}
+	default:
+		return nil, fmt.Errorf("invalid -mode %q: expected http, ws or grpc", cfg.Mode)
+	}
+	if err := validateURL(cfg.URL, cfg.Mode); err != nil {
+		return nil, err
+	}
+	if len(cfg.Resolve) > 0 {
+		resolves, err := parseResolves(cfg.Resolve)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -resolve: %v", err)
+		}
+		cfg.resolves = resolves
+	}
+	if cfg.SourcePorts != "" {
+		ports, err := parseSourcePorts(cfg.SourcePorts)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -source-ports: %v", err)
+		}
+		cfg.sourcePorts = ports
+	}
+	if cfg.ReuseAddr && !reuseAddrSupported {
+		return nil, fmt.Errorf("-reuseaddr is not supported on this platform")
+	}
+	if (cfg.SourcePorts != "" || cfg.ReuseAddr) && cfg.Mode != "http" {
+		return nil, fmt.Errorf("-source-ports and -reuseaddr only work with -mode http")
+	}
+	switch cfg.HTTPVersion {
+	case "", "1.1":
+	case "2":
+		// Cleartext HTTP/2 needs h2c, which the standard transport lacks.
+		if !strings.HasPrefix(cfg.URL, "https://") {
+			return nil, fmt.Errorf("-http-version 2 requires an https -url")
+		}
+	default:
+		return nil, fmt.Errorf("invalid -http-version %q: expected 1.1 or 2", cfg.HTTPVersion)
+	}
+	if cfg.URLTemplate != "" && (cfg.Plan != "" || cfg.Mode != "http") {
+		return nil, fmt.Errorf("-url-template cannot be combined with -plan and only works with -mode http")
+	}
+
+	if cfg.MaxPerHost < 0 {
+		return nil, fmt.Errorf("-max-per-host must not be negative")
+	}
+	if cfg.MaxPerHost > 0 && cfg.Mode != "http" {
+		return nil, fmt.Errorf("-max-per-host only works with -mode http")
+	}
+
+	// HTTP/2 frames every body; there is no chunked encoding to test.
+	if cfg.Chunked && cfg.HTTPVersion == "2" {
+		return nil, fmt.Errorf("-chunked needs HTTP/1.1 and cannot be combined with -http-version 2")
+	}
+
+	if cfg.Requests < 1 {
+		return nil, fmt.Errorf("-requests must be at least 1")
+	}
+	if cfg.Concurrency < 1 {
+		return nil, fmt.Errorf("-concurrency must be at least 1")
+	}
+	if cfg.Queue < 0 {
+		return nil, fmt.Errorf("-queue cannot be negative")
+	}
+
+	if cfg.BreakerThreshold < 0 || cfg.BreakerThreshold >= 1 {
+		return nil, fmt.Errorf("-breaker-threshold must be between 0 and 1")
+	}
+	if cfg.BreakerThreshold > 0 && cfg.BreakerWindow < 1 {
+		return nil, fmt.Errorf("-breaker-window must be at least 1")
+	}
+
+	switch cfg.Format {
+	case "multipart", "ndjson":
+	default:
+		return nil, fmt.Errorf("invalid -format %q: expected multipart or ndjson", cfg.Format)
+	}
+	if cfg.BatchSize < 1 {
+		return nil, fmt.Errorf("-batch must be at least 1")
+	}
+	if cfg.BatchSize > 1 && (cfg.Plan != "" || cfg.PartitionImages) {
+		return nil, fmt.Errorf("-batch cannot be combined with -plan or -partition-images")
+	}
+	if cfg.ChunkSize > 0 && (cfg.BatchSize > 1 || cfg.Format != "multipart") {
+		return nil, fmt.Errorf("-chunk-size only works with single-image multipart requests")
+	}
+
+	if cfg.AssertMemoryStable && cfg.Cooldown <= 0 {
+		return nil, fmt.Errorf("-assert-memory-stable needs a positive -cooldown")
+	}
+	if cfg.MemoryTolerance < 0 {
+		return nil, fmt.Errorf("-memory-tolerance must not be negative")
+	}
+
+	switch cfg.Arrival {
+	case "", "poisson", "uniform":
+	default:
+		return nil, fmt.Errorf("invalid -arrival %q: expected poisson or uniform", cfg.Arrival)
+	}
+	if cfg.Arrival != "" && cfg.ArrivalMean <= 0 {
+		return nil, fmt.Errorf("-arrival-mean must be positive")
+	}
+	if cfg.Burst < 0 {
+		return nil, fmt.Errorf("-burst must not be negative")
+	}
+	if cfg.Burst > 0 {
+		if cfg.Arrival != "" {
+			return nil, fmt.Errorf("-burst and -arrival cannot be combined")
+		}
+		if cfg.BurstInterval <= 0 {
+			return nil, fmt.Errorf("-burst-interval must be positive")
+		}
+	}
+
+	switch cfg.SizeWeighted {
+	case "", "direct", "inverse":
+	default:
+		return nil, fmt.Errorf("invalid -size-weighted %q: expected direct or inverse", cfg.SizeWeighted)
+	}
+	if cfg.SizeWeighted != "" && (cfg.Plan != "" || cfg.PartitionImages) {
+		return nil, fmt.Errorf("-size-weighted cannot be combined with -plan or -partition-images")
+	}
+
+	for _, value := range cfg.SLO {
+		slo, err := parseSLO(value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -slo: %v", err)
+		}
+		cfg.slos = append(cfg.slos, slo)
+	}
+
+	if cfg.ErrorPath != "" {
+		path, err := parseJSONPath(cfg.ErrorPath)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -error-path: %v", err)
+		}
+		cfg.errorPath = path
+		if cfg.ErrorTop < 1 {
+			return nil, fmt.Errorf("-error-top must be at least 1")
+		}
+	}
+
+	if len(cfg.Folder) == 0 {
+		cfg.Folder = stringList{"1"}
+	}
+	folders, err := parseFolders(cfg.Folder)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -folder: %v", err)
+	}
+	if len(folders) > 1 && (cfg.SizeWeighted != "" || cfg.PartitionImages) {
+		return nil, fmt.Errorf("several -folder entries cannot be combined with -size-weighted or -partition-images")
+	}
+	cfg.folders = folders
+
+	if cfg.Seed == 0 {
+		cfg.Seed = uint64(time.Now().UnixNano())
+	}
+
+	if cfg.Plan != "" && cfg.PartitionImages {
+		return nil, fmt.Errorf("-partition-images cannot be combined with -plan")
+	}
+
+	switch cfg.Conditional {
+	case "", "if-none-match", "if-match":
+	default:
+		return nil, fmt.Errorf("invalid -conditional %q: expected if-none-match or if-match", cfg.Conditional)
+	}
+
+	if cfg.VerifyGet != "" {
+		if !strings.Contains(cfg.VerifyGet, "{id}") {
+			return nil, fmt.Errorf("-verify-get must contain an {id} placeholder")
+		}
+		if cfg.VerifySample <= 0 || cfg.VerifySample > 1 {
+			return nil, fmt.Errorf("-verify-sample must be greater than 0 and at most 1")
+		}
+	}
+
+	if cfg.ExpectLength != "" {
+		bounds, err := parseLengthRange(cfg.ExpectLength)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -expect-length: %v", err)
+		}
+		cfg.expectLength = bounds
+	}
+	if cfg.ExpectContentType != "" {
+		types, err := parseContentTypes(cfg.ExpectContentType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -expect-content-type: %v", err)
+		}
+		if cfg.Mode != "http" {
+			return nil, fmt.Errorf("-expect-content-type only works with -mode http")
+		}
+		cfg.contentTypes = types
+	}
+
+	if cfg.TargetErrors < 0 || cfg.TargetErrors > 1 {
+		return nil, fmt.Errorf("-target-errors must be between 0 and 1")
+	}
+	if cfg.TargetErrors > 0 {
+		if cfg.Mode != "http" {
+			return nil, fmt.Errorf("-target-errors only works with -mode http")
+		}
+		corruptions, err := parseCorruptions(cfg.Corruptions)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -corruptions: %v", err)
+		}
+		cfg.corruptions = corruptions
+
+		rejectCodes, err := parseStatusCodes(cfg.RejectCodes)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -reject-codes: %v", err)
+		}
+		cfg.rejectCodes = rejectCodes
+	}
+
+	if cfg.Boundary != "" {
+		if err := multipart.NewWriter(io.Discard).SetBoundary(cfg.Boundary); err != nil {
+			return nil, fmt.Errorf("invalid -boundary %q: must be 1-70 characters from RFC 2046 bchars and not end in a space", cfg.Boundary)
+		}
+	}
+
+	successCodes, err := parseStatusCodes(cfg.SuccessCodes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -success-codes: %v", err)
+	}
+	cfg.successCodes = successCodes
+
+	for _, rule := range cfg.Classify {
+		classifier, err := parseClassifier(rule)
+		if err != nil {
+			return nil, fmt.Errorf("invalid -classify: %v", err)
+		}
+		registerClassifier(classifier)
+	}
+
+	switch cfg.Checksum {
+	case "":
+	case "md5", "sha256":
+		if cfg.ChecksumHeader == "" {
+			cfg.ChecksumHeader = defaultChecksumHeader(cfg.Checksum)
+		}
+		switch cfg.ChecksumScope {
+		case "file":
+			if cfg.BatchSize > 1 {
+				return nil, fmt.Errorf("-checksum-scope file covers a single image; use body with -batch")
+			}
+		case "body":
+		default:
+			return nil, fmt.Errorf("invalid -checksum-scope %q: expected file or body", cfg.ChecksumScope)
+		}
+		registerClassifier(classifyChecksumRejection)
+	default:
+		return nil, fmt.Errorf("invalid -checksum %q: expected md5 or sha256", cfg.Checksum)
+	}
+	if cfg.Chunked {
+		registerClassifier(classifyChunkedRejection)
+	}
+
+	formFields, err := parseFormFields(cfg.FormFields)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -form: %v", err)
+	}
+	cfg.formFields = formFields
+
+	loginFields, err := parseFormFields(cfg.LoginForm)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -login-form: %v", err)
+	}
+	if len(loginFields) > 0 && cfg.LoginURL == "" {
+		return nil, fmt.Errorf("-login-form needs -login-url")
+	}
+	cfg.loginFields = loginFields
+
+	if cfg.OAuthTokenURL != "" {
+		if cfg.ClientID == "" {
+			return nil, fmt.Errorf("-oauth-token-url needs -client-id")
+		}
+		if cfg.Mode != "http" {
+			return nil, fmt.Errorf("-oauth-token-url only works with -mode http")
+		}
+	} else if cfg.ClientID != "" || cfg.ClientSecret != "" || cfg.Scope != "" {
+		return nil, fmt.Errorf("-client-id, -client-secret and -scope need -oauth-token-url")
+	}
+
+	labels, err := parseLabels(cfg.Labels, cfg.OpenMetricsOutput != "")
+	if err != nil {
+		return nil, fmt.Errorf("invalid -label: %v", err)
+	}
+	cfg.labels = labels
+
+	parts, err := parseParts(cfg.Parts)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -part: %v", err)
+	}
+	cfg.parts = parts
+
+	return cfg, nil
+}
+
+func parseFormFields(values []string) ([]formField, error) {
+	var fields []formField
+	for _, value := range values {
+		key, fieldValue, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", value)
+		}
+
+		fieldValue = os.ExpandEnv(fieldValue)
+		if strings.HasPrefix(fieldValue, "@") {
+			data, err := os.ReadFile(fieldValue[1:])
+			if err != nil {
+				return nil, fmt.Errorf("error reading value for %s: %v", key, err)
+			}
+			fieldValue = string(data)
+		}
+
+		fields = append(fields, formField{key: key, value: fieldValue})
+	}
+	return fields, nil
+}
+
+var bulkEndpointPattern = regexp.MustCompile(`^/api/v1/faceLists/[^/]+/faces/bulk/?$`)
+
+func parseParts(values []string) ([]namedPart, error) {
+	var parts []namedPart
+	for _, value := range values {
+		field, path, ok := strings.Cut(value, "=")
+		if !ok || field == "" || path == "" {
+			return nil, fmt.Errorf("expected field=path, got %q", value)
+		}
+
+		image, err := loadImageFromFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("part %s: %v", field, err)
+		}
+		parts = append(parts, namedPart{field: field, image: image})
+	}
+	return parts, nil
+}
+
+// applyEnvDefaults lets orchestrators set a few values without templating
+// flags. Precedence, lowest first: built-in defaults, environment, -config
+// file, command-line flags.
+func applyEnvDefaults(cfg *Config) error {
+	envInts := []struct {
+		name  string
+		value *int
+	}{
+		{"CONCURRENCY", &cfg.Concurrency},
+		{"TOTAL_REQUESTS", &cfg.Requests},
+	}
+	for _, env := range envInts {
+		raw, ok := os.LookupEnv(env.name)
+		if !ok || raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(raw))
+		if err != nil {
+			return fmt.Errorf("invalid %s=%q: not an integer", env.name, raw)
+		}
+		*env.value = value
+	}
+	return nil
+}
+
+// unhashedFlags don't shape the load, so runs that differ only in them
+// still group together: labels describe the run, the rest choose where
+// results go and how the run is shown. Secrets are left out as well, since
+// the hash is published with every report, and a rotated secret doesn't
+// change the configuration.
+var unhashedFlags = map[string]bool{
+	"label": true,
+
+	"json": true, "format-compat": true, "csv": true, "events": true, "sqlite": true, "sqlite-requests": true,
+	"openmetrics": true, "failures-file": true, "report-file": true, "report-interval": true, "sweep-csv": true,
+	"soak-interval": true, "soak-file": true, "slowest": true, "error-top": true, "phase-breakdown": true,
+	"dump-headers": true, "dump-secrets": true, "pprof": true, "config": true,
+
+	"yes": true, "interactive": true, "tui": true,
+
+	"client-secret": true, "transform-key": true, "login-form": true,
+}
+
+// hashFlags fingerprints every flag value that shapes the load as given,
+// before defaults such as the clock seed are filled in, so runs with the
+// same settings can be grouped.
+func hashFlags() string {
+	hash := sha256.New()
+	flag.VisitAll(func(f *flag.Flag) {
+		if unhashedFlags[f.Name] {
+			return
+		}
+		fmt.Fprintf(hash, "%s=%s\n", f.Name, f.Value)
+	})
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+func validateURL(rawURL string, mode string) error {
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid -url %q: %v", rawURL, err)
+	}
+	switch mode {
+	case "ws":
+		if target.Scheme != "ws" && target.Scheme != "wss" {
+			return fmt.Errorf("invalid -url %q: scheme must be ws or wss with -mode ws", rawURL)
+		}
+	case "grpc":
+		if target.Scheme != "grpc" && target.Scheme != "grpcs" {
+			return fmt.Errorf("invalid -url %q: scheme must be grpc or grpcs with -mode grpc", rawURL)
+		}
+	default:
+		if target.Scheme != "http" && target.Scheme != "https" {
+			return fmt.Errorf("invalid -url %q: scheme must be http or https", rawURL)
+		}
+	}
+	if target.Host == "" {
+		return fmt.Errorf("invalid -url %q: missing host", rawURL)
+	}
+
+	if strings.Contains(strings.ToLower(target.Hostname()), "axxonnet") && !bulkEndpointPattern.MatchString(target.Path) {
+		fmt.Printf("Warning: %s does not look like the bulk upload endpoint (/api/v1/faceLists/<id>/faces/bulk)\n", target.Path)
+	}
+	return nil
+}
+
+func parseStatusCodes(value string) (map[int]bool, error) {
+	codes := make(map[int]bool)
+	for _, field := range strings.Split(value, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		code, err := strconv.Atoi(field)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("bad status code %q", field)
+		}
+		codes[code] = true
+	}
+
+	if len(codes) == 0 {
+		return nil, fmt.Errorf("no status codes given")
+	}
+
+	return codes, nil
+}
+
+type ByteSize int64
+
+var byteSizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"GB", 1 << 30},
+	{"MB", 1 << 20},
+	{"KB", 1 << 10},
+	{"B", 1},
+}
+
+func (size *ByteSize) String() string {
+	return strconv.FormatInt(int64(*size), 10)
+}
+
+func (size *ByteSize) Set(value string) error {
+	value = strings.ToUpper(strings.TrimSpace(value))
+	multiplier := int64(1)
+	for _, unit := range byteSizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+
+	number, err := strconv.ParseFloat(value, 64)
+	if err != nil || number < 0 {
+		return fmt.Errorf("invalid size %q", value)
+	}
+
+	*size = ByteSize(number * float64(multiplier))
+	return nil
+}
+
+func (size *ByteSize) UnmarshalText(text []byte) error {
+	return size.Set(string(text))
+}
+
+type stringList []string
+
+func (list *stringList) String() string {
+	return strings.Join(*list, ",")
+}
+
+func (list *stringList) Set(value string) error {
+	*list = append(*list, value)
+	return nil
+}
+
+// UnmarshalTOML takes a single string as well as an array, so a flag that
+// became repeatable keeps reading config files written for the old one.
+func (list *stringList) UnmarshalTOML(value any) error {
+	switch value := value.(type) {
+	case string:
+		*list = stringList{value}
+	case []any:
+		*list = nil
+		for _, item := range value {
+			text, ok := item.(string)
+			if !ok {
+				return fmt.Errorf("expected strings, got %T", item)
+			}
+			*list = append(*list, text)
+		}
+	default:
+		return fmt.Errorf("expected a string or an array of strings, got %T", value)
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestParseStatusCodes(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    map[int]bool
+		wantErr bool
+	}{
+		{value: "200", want: map[int]bool{200: true}},
+		{value: "200,201,202", want: map[int]bool{200: true, 201: true, 202: true}},
+		{value: " 201 , 202 ", want: map[int]bool{201: true, 202: true}},
+		{value: "200,,", want: map[int]bool{200: true}},
+		{value: "", wantErr: true},
+		{value: ",", wantErr: true},
+		{value: "ok", wantErr: true},
+		{value: "99", wantErr: true},
+		{value: "600", wantErr: true},
+		{value: "200,2O1", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseStatusCodes(test.value)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseStatusCodes(%q) = %v, want an error", test.value, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseStatusCodes(%q) failed: %v", test.value, err)
+			continue
+		}
+		if !maps.Equal(got, test.want) {
+			t.Errorf("parseStatusCodes(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestParseFormFields(t *testing.T) {
+	valueFile := filepath.Join(t.TempDir(), "label.txt")
+	if err := os.WriteFile(valueFile, []byte("from a file"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("UPLOADER_TEST_LIST", "42")
+
+	tests := []struct {
+		values  []string
+		want    []formField
+		wantErr bool
+	}{
+		{values: []string{"faceListId=1"}, want: []formField{{key: "faceListId", value: "1"}}},
+		{values: []string{"label=a=b", "empty="}, want: []formField{{key: "label", value: "a=b"}, {key: "empty", value: ""}}},
+		{values: []string{"faceListId=$UPLOADER_TEST_LIST"}, want: []formField{{key: "faceListId", value: "42"}}},
+		{values: []string{"label=@" + valueFile}, want: []formField{{key: "label", value: "from a file"}}},
+		{values: []string{"label"}, wantErr: true},
+		{values: []string{"=value"}, wantErr: true},
+		{values: []string{"label=@" + filepath.Join(t.TempDir(), "missing")}, wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := parseFormFields(test.values)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseFormFields(%q) = %v, want an error", test.values, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseFormFields(%q) failed: %v", test.values, err)
+			continue
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("parseFormFields(%q) = %v, want %v", test.values, got, test.want)
+		}
+	}
+}
+
+func TestParseParts(t *testing.T) {
+	dir := t.TempDir()
+	for name, data := range map[string]string{"mask.png": "mask", "meta.json": `{"id":1}`} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	mask, meta := filepath.Join(dir, "mask.png"), filepath.Join(dir, "meta.json")
+
+	type part struct{ field, name, data string }
+	tests := []struct {
+		values  []string
+		want    []part
+		wantErr bool
+	}{
+		{values: nil, want: nil},
+		{values: []string{"mask=" + mask}, want: []part{{"mask", "mask.png", "mask"}}},
+		{values: []string{"mask=" + mask, "metadata=" + meta}, want: []part{{"mask", "mask.png", "mask"}, {"metadata", "meta.json", `{"id":1}`}}},
+		{values: []string{mask}, wantErr: true},
+		{values: []string{"=" + mask}, wantErr: true},
+		{values: []string{"mask="}, wantErr: true},
+		{values: []string{"mask=" + filepath.Join(dir, "missing.png")}, wantErr: true},
+	}
+	for _, test := range tests {
+		parts, err := parseParts(test.values)
+		if test.wantErr {
+			if err == nil {
+				t.Errorf("parseParts(%q) succeeded, want an error", test.values)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseParts(%q) failed: %v", test.values, err)
+			continue
+		}
+		var got []part
+		for _, p := range parts {
+			got = append(got, part{p.field, p.image.name, string(p.image.data)})
+		}
+		if !slices.Equal(got, test.want) {
+			t.Errorf("parseParts(%q) = %v, want %v", test.values, got, test.want)
+		}
+	}
+}
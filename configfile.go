@@ -0,0 +1,66 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// findConfigFlag looks for -config in the raw arguments so the file can be
+// loaded before flag.Parse; anything given on the command line then
+// overrides the file.
+func findConfigFlag(args []string) string {
+	for i, arg := range args {
+		if arg == "--" {
+			break
+		}
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name != "config" {
+			continue
+		}
+		if hasValue {
+			return value
+		}
+		if i+1 < len(args) {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// loadConfigFile decodes the config file into cfg and returns the keys it
+// set. Keys are the flag names, e.g. max-conns-per-host = 20; repeatable
+// flags such as form take an array, and command-line values are appended.
+func loadConfigFile(path string, cfg *Config) (map[string]bool, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+	default:
+		return nil, fmt.Errorf("config %s: unsupported format (expected .toml)", path)
+	}
+
+	meta, err := toml.DecodeFile(path, cfg)
+	if err != nil {
+		var parseErr toml.ParseError
+		if errors.As(err, &parseErr) {
+			return nil, fmt.Errorf("config %s: line %d (key %q): %s", path, parseErr.Position.Line, parseErr.LastKey, parseErr.Message)
+		}
+		return nil, fmt.Errorf("config %s: %w", path, err)
+	}
+
+	if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+		names := make([]string, len(undecoded))
+		for i, key := range undecoded {
+			names[i] = key.String()
+		}
+		return nil, fmt.Errorf("config %s: unknown keys: %s", path, strings.Join(names, ", "))
+	}
+
+	keys := map[string]bool{}
+	for _, key := range meta.Keys() {
+		keys[key.String()] = true
+	}
+	return keys, nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
+)
+
+// productionWords mark a hostname label as production: prod.example.com,
+// api-prod.example.com, live.example.com.
+var productionWords = []string{"prod", "production", "live"}
+
+// looksLikeProduction guesses from the hostname alone; IP addresses and
+// local names are never production.
+func looksLikeProduction(host string) bool {
+	host = strings.ToLower(host)
+	if host == "localhost" || net.ParseIP(host) != nil {
+		return false
+	}
+	for _, suffix := range []string{".localhost", ".local", ".test", ".internal"} {
+		if strings.HasSuffix(host, suffix) {
+			return false
+		}
+	}
+	words := strings.FieldsFunc(host, func(r rune) bool { return r == '.' || r == '-' || r == '_' })
+	return slices.ContainsFunc(words, func(word string) bool { return slices.Contains(productionWords, word) })
+}
+
+// confirmRun asks before sending load with -interactive, and always for a
+// production-looking host unless -yes is given. Without a terminal to ask
+// on, a run that needs confirmation is refused rather than started.
+func confirmRun(cfg *Config, totalRequests int, payloadBytes int64) bool {
+	if cfg.Yes {
+		return true
+	}
+	target, _ := url.Parse(cfg.URL)
+	production := looksLikeProduction(target.Hostname())
+	if !cfg.Interactive && !production {
+		return true
+	}
+
+	fmt.Printf("\nAbout to upload to %s\n", cfg.URL)
+	fmt.Printf("  host:        %s\n", target.Host)
+	switch {
+	case cfg.Forever:
+		fmt.Printf("  requests:    until stopped (-forever)\n")
+		fmt.Printf("  concurrency: %d\n", cfg.Concurrency)
+	case cfg.ConcurrencySweep != "":
+		fmt.Printf("  requests:    %v per level\n", cfg.SweepStep)
+		fmt.Printf("  concurrency: %s\n", cfg.ConcurrencySweep)
+	default:
+		fmt.Printf("  requests:    %d\n", totalRequests)
+		fmt.Printf("  concurrency: %d\n", cfg.Concurrency)
+		fmt.Printf("  payload:     %s\n", formatBytes(payloadBytes))
+	}
+	if production {
+		fmt.Printf("Warning: %s looks like a production host\n", target.Hostname())
+	}
+
+	if cfg.Stdin || !isTerminal(os.Stdin) {
+		fmt.Printf("No terminal to confirm on; pass -yes to run without confirmation\n")
+		return false
+	}
+	fmt.Printf("Proceed? [y/N] ")
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
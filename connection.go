@@ -0,0 +1,215 @@
+package main
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+const requestTimeout = 30 * time.Second
+
+// defaultDialer has the same timeouts as http.DefaultTransport's dialer, for
+// the dialers this tool installs in its place.
+func defaultDialer() *net.Dialer {
+	return &net.Dialer{Timeout: 30 * time.Second, KeepAlive: 30 * time.Second}
+}
+
+func newHTTPClient(cfg *Config, dial dialFunc) *http.Client {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.MaxConnsPerHost = cfg.MaxConnsPerHost
+	if cfg.prewarmConns > 0 {
+		// Past the default of two idle connections per host, the pre-warmed
+		// ones would be closed as soon as their requests finish.
+		transport.MaxIdleConnsPerHost = cfg.prewarmConns
+		transport.MaxIdleConns = max(transport.MaxIdleConns, cfg.prewarmConns)
+	}
+	if dial != nil {
+		transport.DialContext = dial
+	}
+	switch cfg.HTTPVersion {
+	case "1.1":
+		// A non-nil empty map stops the transport from upgrading to h2.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+		transport.TLSClientConfig = &tls.Config{NextProtos: []string{"http/1.1"}}
+	case "2":
+		transport.ForceAttemptHTTP2 = true
+		transport.TLSClientConfig = &tls.Config{NextProtos: []string{"h2"}}
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   requestTimeout,
+	}
+}
+
+type connectionTrace struct {
+	getConnAt time.Time
+	connWait  time.Duration
+
+	// Dials may finish on the transport's own goroutine, so the addresses
+	// are guarded.
+	addrMutex  sync.Mutex
+	localAddr  string
+	remoteAddr string
+
+	// protocol is set only when the request opened a new connection.
+	protocol string
+	connects int
+
+	// wroteAt and firstByteAt split a request into sending the body,
+	// waiting for the server and reading the response.
+	wroteAt     time.Time
+	firstByteAt time.Time
+
+	// Set only for the parts of a new connection the request waited on.
+	gotConnAt    time.Time
+	dnsStart     time.Time
+	dnsDone      time.Time
+	connectStart time.Time
+	connectDone  time.Time
+	tlsStart     time.Time
+	tlsDone      time.Time
+}
+
+// breakdown splits the request into the phases of -phase-breakdown. Dialing
+// happens while the request waits for a connection, so pool_wait is what
+// remains of that wait after DNS, connect and TLS.
+func (trace *connectionTrace) breakdown(start time.Time, done time.Time) requestBreakdown {
+	trace.addrMutex.Lock()
+	defer trace.addrMutex.Unlock()
+	if trace.gotConnAt.IsZero() || trace.wroteAt.IsZero() || trace.firstByteAt.IsZero() {
+		return requestBreakdown{}
+	}
+	span := func(from, to time.Time) time.Duration {
+		if from.IsZero() || to.IsZero() {
+			return 0
+		}
+		return max(to.Sub(from), 0)
+	}
+	breakdown := requestBreakdown{
+		dns:      span(trace.dnsStart, trace.dnsDone),
+		connect:  span(trace.connectStart, trace.connectDone),
+		tls:      span(trace.tlsStart, trace.tlsDone),
+		send:     span(trace.gotConnAt, trace.wroteAt),
+		server:   span(trace.wroteAt, trace.firstByteAt),
+		transfer: span(trace.firstByteAt, done),
+	}
+	breakdown.poolWait = max(span(start, trace.gotConnAt)-breakdown.dns-breakdown.connect-breakdown.tls, 0)
+	return breakdown
+}
+
+// phases splits the span from start to done at the moment the request was
+// fully written and the moment the first response byte arrived. A server
+// that answers before reading the whole body gets no waiting time rather
+// than a negative one.
+func (trace *connectionTrace) phases(start time.Time, done time.Time) (upload, server, download time.Duration) {
+	trace.addrMutex.Lock()
+	defer trace.addrMutex.Unlock()
+	if trace.wroteAt.IsZero() || trace.firstByteAt.IsZero() {
+		return 0, 0, 0
+	}
+	upload = trace.wroteAt.Sub(start)
+	server = max(trace.firstByteAt.Sub(trace.wroteAt), 0)
+	download = done.Sub(trace.firstByteAt)
+	return upload, server, download
+}
+
+func (trace *connectionTrace) addresses() (local string, remote string) {
+	trace.addrMutex.Lock()
+	defer trace.addrMutex.Unlock()
+	return trace.localAddr, trace.remoteAddr
+}
+
+// newConnections counts the TCP connections this request established.
+func (trace *connectionTrace) newConnections() int {
+	trace.addrMutex.Lock()
+	defer trace.addrMutex.Unlock()
+	return trace.connects
+}
+
+func (trace *connectionTrace) newProtocol() string {
+	trace.addrMutex.Lock()
+	defer trace.addrMutex.Unlock()
+	return trace.protocol
+}
+
+func connectionProtocol(conn net.Conn) string {
+	if tlsConn, ok := conn.(*tls.Conn); ok {
+		if protocol := tlsConn.ConnectionState().NegotiatedProtocol; protocol != "" {
+			return protocol
+		}
+	}
+	return "http/1.1"
+}
+
+func (trace *connectionTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			trace.getConnAt = time.Now()
+		},
+		DNSStart: func(httptrace.DNSStartInfo) {
+			trace.addrMutex.Lock()
+			trace.dnsStart = time.Now()
+			trace.addrMutex.Unlock()
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			trace.addrMutex.Lock()
+			trace.dnsDone = time.Now()
+			trace.addrMutex.Unlock()
+		},
+		ConnectStart: func(network string, addr string) {
+			trace.addrMutex.Lock()
+			trace.remoteAddr = addr
+			// Dual-stack dialing can start several attempts; time from the first.
+			if trace.connectStart.IsZero() {
+				trace.connectStart = time.Now()
+			}
+			trace.addrMutex.Unlock()
+		},
+		ConnectDone: func(network string, addr string, err error) {
+			if err != nil {
+				return
+			}
+			trace.addrMutex.Lock()
+			trace.connects++
+			trace.connectDone = time.Now()
+			trace.addrMutex.Unlock()
+		},
+		TLSHandshakeStart: func() {
+			trace.addrMutex.Lock()
+			trace.tlsStart = time.Now()
+			trace.addrMutex.Unlock()
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			trace.addrMutex.Lock()
+			trace.tlsDone = time.Now()
+			trace.addrMutex.Unlock()
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			trace.connWait = time.Since(trace.getConnAt)
+			trace.addrMutex.Lock()
+			trace.gotConnAt = time.Now()
+			trace.localAddr = info.Conn.LocalAddr().String()
+			trace.remoteAddr = info.Conn.RemoteAddr().String()
+			if !info.Reused {
+				trace.protocol = connectionProtocol(info.Conn)
+			}
+			trace.addrMutex.Unlock()
+		},
+		// WroteRequest runs on the transport's writing goroutine.
+		WroteRequest: func(httptrace.WroteRequestInfo) {
+			trace.addrMutex.Lock()
+			trace.wroteAt = time.Now()
+			trace.addrMutex.Unlock()
+		},
+		GotFirstResponseByte: func() {
+			trace.addrMutex.Lock()
+			trace.firstByteAt = time.Now()
+			trace.addrMutex.Unlock()
+		},
+	}
+}
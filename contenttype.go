@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+const categoryContentType = "content_type"
+
+// parseContentTypes reads the comma-separated media types of
+// -expect-content-type. Parameters such as charset are dropped; only the
+// type and subtype are compared.
+func parseContentTypes(value string) (map[string]bool, error) {
+	types := make(map[string]bool)
+	for _, field := range strings.Split(value, ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(field))
+		if err != nil || !strings.Contains(mediaType, "/") {
+			return nil, fmt.Errorf("expected media types such as application/json, got %q", field)
+		}
+		types[mediaType] = true
+	}
+	return types, nil
+}
+
+// contentTypeMatches reports whether a response Content-Type header is one
+// of the expected types, case-insensitively and whatever its parameters;
+// a malformed parameter still leaves the type to compare. A missing or
+// unparsable header never matches.
+func contentTypeMatches(header string, expected map[string]bool) bool {
+	mediaType, _, err := mime.ParseMediaType(header)
+	if err != nil && err != mime.ErrInvalidMediaParameter {
+		return false
+	}
+	return expected[mediaType]
+}
@@ -0,0 +1,30 @@
+//go:build unix
+
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+func watchConcurrencySignals(pool *workerPool) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGUSR1, syscall.SIGUSR2)
+
+	fmt.Printf("Send SIGUSR1/SIGUSR2 to pid %d to increase/decrease concurrency\n", os.Getpid())
+
+	go func() {
+		for sig := range signals {
+			previous := pool.currentSize()
+			var current int
+			if sig == syscall.SIGUSR1 {
+				current = pool.resize(previous + 1)
+			} else {
+				current = pool.resize(previous - 1)
+			}
+			fmt.Printf("Concurrency changed: %d -> %d\n", previous, current)
+		}
+	}()
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const (
+	dashboardRefresh     = 500 * time.Millisecond
+	dashboardMemoryEvery = 2 * time.Second
+	dashboardWindow      = 1024
+	dashboardLogLines    = 8
+)
+
+// dashboard redraws a live view on the terminal. While it runs, os.Stdout
+// is swapped for a pipe so the usual per-request log lines land in a panel
+// instead of scrolling over the screen; Finish puts stdout back before the
+// console summary is printed. Paths that exit without Finish call
+// restoreTerminal instead.
+type dashboard struct {
+	terminal *os.File
+	pipe     *os.File
+	start    time.Time
+
+	mutex       sync.Mutex
+	completed   int
+	successes   int
+	categories  map[string]int
+	recent      [dashboardWindow]time.Duration
+	recentCount int
+	lastSecond  []time.Time
+	logLines    []string
+	memory      uint64
+	hasMemory   bool
+
+	done     chan struct{}
+	finished sync.WaitGroup
+	restored sync.Once
+	signals  chan os.Signal
+}
+
+// liveDashboard is the dashboard currently holding the terminal, if any.
+var liveDashboard struct {
+	mutex sync.Mutex
+	dash  *dashboard
+}
+
+// restoreTerminal leaves the dashboard's alternate screen before an early
+// os.Exit, so the last messages are readable and the shell gets its cursor
+// back. It does nothing when no dashboard is running.
+func restoreTerminal() {
+	liveDashboard.mutex.Lock()
+	dash := liveDashboard.dash
+	liveDashboard.mutex.Unlock()
+	if dash != nil {
+		dash.restore()
+	}
+}
+
+func isTerminal(file *os.File) bool {
+	info, err := file.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// newDashboard returns nil when stdout is not a terminal. memory may be nil
+// when container monitoring is unavailable.
+func newDashboard(memory func() (uint64, error)) (*dashboard, error) {
+	if !isTerminal(os.Stdout) {
+		return nil, nil
+	}
+
+	reader, writer, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("error creating dashboard pipe: %v", err)
+	}
+
+	dash := &dashboard{
+		terminal:   os.Stdout,
+		pipe:       writer,
+		start:      time.Now(),
+		categories: make(map[string]int),
+		done:       make(chan struct{}),
+	}
+	os.Stdout = writer
+	fmt.Fprint(dash.terminal, "\x1b[?1049h\x1b[?25l")
+	liveDashboard.mutex.Lock()
+	liveDashboard.dash = dash
+	liveDashboard.mutex.Unlock()
+
+	dash.finished.Add(2)
+	go dash.collectLogs(reader)
+	go dash.draw()
+	if memory != nil {
+		go dash.pollMemory(memory)
+	}
+	return dash, nil
+}
+
+func (dash *dashboard) collectLogs(reader *os.File) {
+	defer dash.finished.Done()
+	defer reader.Close()
+	scanner := bufio.NewScanner(reader)
+	for scanner.Scan() {
+		dash.mutex.Lock()
+		dash.logLines = append(dash.logLines, scanner.Text())
+		if len(dash.logLines) > dashboardLogLines {
+			dash.logLines = dash.logLines[len(dash.logLines)-dashboardLogLines:]
+		}
+		dash.mutex.Unlock()
+	}
+}
+
+func (dash *dashboard) pollMemory(memory func() (uint64, error)) {
+	ticker := time.NewTicker(dashboardMemoryEvery)
+	defer ticker.Stop()
+	for {
+		if usage, err := memory(); err == nil {
+			dash.mutex.Lock()
+			dash.memory, dash.hasMemory = usage, true
+			dash.mutex.Unlock()
+		}
+		select {
+		case <-dash.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (dash *dashboard) draw() {
+	defer dash.finished.Done()
+	ticker := time.NewTicker(dashboardRefresh)
+	defer ticker.Stop()
+	for {
+		dash.render()
+		select {
+		case <-dash.done:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (dash *dashboard) render() {
+	dash.mutex.Lock()
+	defer dash.mutex.Unlock()
+
+	now := time.Now()
+	cutoff := sort.Search(len(dash.lastSecond), func(i int) bool { return now.Sub(dash.lastSecond[i]) < time.Second })
+	dash.lastSecond = dash.lastSecond[cutoff:]
+	elapsed := now.Sub(dash.start)
+
+	var screen strings.Builder
+	screen.WriteString("\x1b[H\x1b[2J")
+	fmt.Fprintf(&screen, "uploader_test — %v\n\n", elapsed.Round(time.Second))
+	fmt.Fprintf(&screen, "Завершено: %d (успешно %d, ошибок %d)\n", dash.completed, dash.successes, dash.completed-dash.successes)
+	fmt.Fprintf(&screen, "RPS: %d за последнюю секунду, %.1f в среднем\n", len(dash.lastSecond), float64(dash.completed)/elapsed.Seconds())
+
+	window := dash.recent[:min(dash.recentCount, dashboardWindow)]
+	if len(window) > 0 {
+		marks := percentiles(window, 50, 90, 99)
+		fmt.Fprintf(&screen, "Время (последние %d): p50 %v, p90 %v, p99 %v\n", len(window),
+			marks[0].Round(time.Microsecond), marks[1].Round(time.Microsecond), marks[2].Round(time.Microsecond))
+	}
+	if dash.hasMemory {
+		fmt.Fprintf(&screen, "Память контейнера: %.2f MB\n", float64(dash.memory)/1024/1024)
+	}
+
+	if len(dash.categories) > 0 {
+		screen.WriteString("\nОшибки:\n")
+		categories := make([]string, 0, len(dash.categories))
+		for category := range dash.categories {
+			categories = append(categories, category)
+		}
+		sort.Strings(categories)
+		for _, category := range categories {
+			fmt.Fprintf(&screen, "  %s: %d\n", category, dash.categories[category])
+		}
+	}
+
+	if len(dash.logLines) > 0 {
+		screen.WriteString("\nЖурнал:\n")
+		for _, line := range dash.logLines {
+			fmt.Fprintf(&screen, "  %s\n", line)
+		}
+	}
+	fmt.Fprint(dash.terminal, screen.String())
+}
+
+func (dash *dashboard) RecordRequest(result RequestResult) {
+	dash.mutex.Lock()
+	defer dash.mutex.Unlock()
+
+	dash.completed++
+	dash.lastSecond = append(dash.lastSecond, time.Now())
+	if result.Success {
+		dash.successes++
+		dash.recent[dash.recentCount%dashboardWindow] = result.Duration
+		dash.recentCount++
+	} else if !result.Neutral {
+		dash.categories[result.Category]++
+	}
+}
+
+// restoreOnSignal puts the terminal back before a SIGINT or SIGTERM ends
+// the process. With graceful set the run itself handles the first signal
+// and drains, so only the second one exits here.
+func (dash *dashboard) restoreOnSignal(graceful bool) {
+	dash.signals = make(chan os.Signal, 2)
+	signal.Notify(dash.signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		if graceful {
+			if _, ok := <-dash.signals; !ok {
+				return
+			}
+		}
+		received, ok := <-dash.signals
+		if !ok {
+			return
+		}
+		dash.restore()
+		code := 1
+		if number, isNumber := received.(syscall.Signal); isNumber {
+			code = 128 + int(number)
+		}
+		os.Exit(code)
+	}()
+}
+
+func (dash *dashboard) restore() {
+	dash.restored.Do(func() {
+		close(dash.done)
+		os.Stdout = dash.terminal
+		dash.pipe.Close()
+		dash.finished.Wait()
+		fmt.Fprint(dash.terminal, "\x1b[?25h\x1b[?1049l")
+		liveDashboard.mutex.Lock()
+		liveDashboard.dash = nil
+		liveDashboard.mutex.Unlock()
+	})
+}
+
+func (dash *dashboard) Finish(summary Summary) error {
+	dash.restore()
+	if dash.signals != nil {
+		signal.Stop(dash.signals)
+		close(dash.signals)
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/sha256"
+	"sync"
+)
+
+// imageStore keeps one copy of each distinct image content for -dedupe.
+// Files are interned as they are read, so a duplicate's bytes are garbage
+// as soon as it has been hashed, and every name keeps its own ImageFile
+// pointing at the shared slice.
+type imageStore struct {
+	mutex      sync.Mutex
+	contents   map[[sha256.Size]byte][]byte
+	duplicates int
+	saved      int64
+}
+
+func newImageStore() *imageStore {
+	return &imageStore{contents: make(map[[sha256.Size]byte][]byte)}
+}
+
+// intern returns the stored copy of data, storing data itself if its
+// content is new. A nil store returns data unchanged.
+func (store *imageStore) intern(data []byte) []byte {
+	if store == nil {
+		return data
+	}
+	sum := sha256.Sum256(data)
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	if shared, ok := store.contents[sum]; ok {
+		store.duplicates++
+		store.saved += int64(len(data))
+		return shared
+	}
+	store.contents[sum] = data
+	return data
+}
+
+func (store *imageStore) report() (duplicates int, saved int64) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	return store.duplicates, store.saved
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// redactedPrefix is how much of a credential -dump-headers keeps: enough to
+// tell two tokens apart, too little to reuse one.
+const redactedPrefix = 6
+
+// sensitiveHeaders are redacted unless -dump-secrets is set.
+var sensitiveHeaders = []string{"Authorization", "Cookie", "Set-Cookie"}
+
+// dumpHeaders prints the headers of one sampled exchange as a single block,
+// so concurrent workers don't interleave their lines. A nil response means
+// the request failed before one arrived.
+func dumpHeaders(cfg *Config, job uploadJob, req *http.Request, resp *http.Response, err error) {
+	var dump strings.Builder
+	fmt.Fprintf(&dump, "=== %s headers ===\n", job.label())
+	fmt.Fprintf(&dump, "> %s %s\n", req.Method, req.URL)
+	writeHeaders(&dump, "> ", req.Header, cfg.DumpSecrets)
+	if resp == nil {
+		fmt.Fprintf(&dump, "< error: %v\n", err)
+	} else {
+		fmt.Fprintf(&dump, "< %s %s\n", resp.Proto, resp.Status)
+		writeHeaders(&dump, "< ", resp.Header, cfg.DumpSecrets)
+	}
+	fmt.Print(dump.String())
+}
+
+func writeHeaders(dump *strings.Builder, marker string, header http.Header, secrets bool) {
+	names := make([]string, 0, len(header))
+	for name := range header {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		for _, value := range header[name] {
+			if !secrets && slices.Contains(sensitiveHeaders, name) {
+				value = redactHeaderValue(value)
+			}
+			fmt.Fprintf(dump, "%s%s: %s\n", marker, name, value)
+		}
+	}
+}
+
+// redactHeaderValue keeps an auth scheme such as Bearer and the first few
+// characters of the secret after it.
+func redactHeaderValue(value string) string {
+	scheme, secret, found := strings.Cut(value, " ")
+	if !found {
+		scheme, secret = "", value
+	} else {
+		scheme += " "
+	}
+	if len(secret) <= redactedPrefix {
+		return scheme + "[redacted]"
+	}
+	return scheme + secret[:redactedPrefix] + "...[redacted]"
+}
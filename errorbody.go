@@ -0,0 +1,136 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// errorSnippetLength bounds the raw-body fallback of -error-path, enough to
+// tell error pages apart without one message per request.
+const errorSnippetLength = 80
+
+type jsonPathStep struct {
+	key   string
+	index int // used when key is empty
+}
+
+// jsonPath is the $.error.message style subset -error-path accepts: object
+// keys separated by dots and array indexes in brackets, as in
+// $.errors[0].detail.
+type jsonPath []jsonPathStep
+
+func parseJSONPath(value string) (jsonPath, error) {
+	rest, ok := strings.CutPrefix(value, "$")
+	if !ok {
+		return nil, fmt.Errorf("expected a path starting with $, got %q", value)
+	}
+	var path jsonPath
+	for rest != "" {
+		switch rest[0] {
+		case '.':
+			end := strings.IndexAny(rest[1:], ".[") + 1
+			if end == 0 {
+				end = len(rest)
+			}
+			if end == 1 {
+				return nil, fmt.Errorf("empty key in %q", value)
+			}
+			path = append(path, jsonPathStep{key: rest[1:end]})
+			rest = rest[end:]
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end < 0 {
+				return nil, fmt.Errorf("unclosed [ in %q", value)
+			}
+			index, err := strconv.Atoi(rest[1:end])
+			if err != nil || index < 0 {
+				return nil, fmt.Errorf("expected an array index in %q, got %q", value, rest[1:end])
+			}
+			path = append(path, jsonPathStep{index: index})
+			rest = rest[end+1:]
+		default:
+			return nil, fmt.Errorf("expected . or [ in %q at %q", value, rest)
+		}
+	}
+	if len(path) == 0 {
+		return nil, fmt.Errorf("path %q selects the whole body", value)
+	}
+	return path, nil
+}
+
+// lookup returns the value at path as text; strings are returned as they
+// are and anything else as compact JSON.
+func (path jsonPath) lookup(body []byte) (string, bool) {
+	var value any
+	if err := json.Unmarshal(body, &value); err != nil {
+		return "", false
+	}
+	for _, step := range path {
+		switch node := value.(type) {
+		case map[string]any:
+			if step.key == "" {
+				return "", false
+			}
+			value = node[step.key]
+		case []any:
+			if step.key != "" || step.index >= len(node) {
+				return "", false
+			}
+			value = node[step.index]
+		default:
+			return "", false
+		}
+	}
+	switch value := value.(type) {
+	case nil:
+		return "", false
+	case string:
+		return value, true
+	default:
+		text, _ := json.Marshal(value)
+		return string(text), true
+	}
+}
+
+// errorMessage names a failure body for -error-path: the value at the path,
+// or else the start of the body with whitespace collapsed.
+func (path jsonPath) errorMessage(body []byte) string {
+	if message, ok := path.lookup(body); ok {
+		return message
+	}
+	snippet := strings.Join(strings.Fields(string(body)), " ")
+	if snippet == "" {
+		return "(empty body)"
+	}
+	if len(snippet) > errorSnippetLength {
+		snippet = strings.ToValidUTF8(snippet[:errorSnippetLength], "") + "..."
+	}
+	return "raw: " + snippet
+}
+
+type ErrorMessage struct {
+	Message string `json:"message"`
+	Count   int    `json:"count"`
+}
+
+// topErrorMessages returns the limit most frequent failure messages, ties
+// in message order so repeated runs list them the same way.
+func (stats *RequestStats) topErrorMessages(limit int) []ErrorMessage {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	messages := make([]ErrorMessage, 0, len(stats.errorMessages))
+	for message, count := range stats.errorMessages {
+		messages = append(messages, ErrorMessage{Message: message, Count: count})
+	}
+	slices.SortFunc(messages, func(a, b ErrorMessage) int {
+		if a.Count != b.Count {
+			return b.Count - a.Count
+		}
+		return strings.Compare(a.Message, b.Message)
+	})
+	return messages[:min(limit, len(messages))]
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+type requestEvent struct {
+	Timestamp  time.Time `json:"ts"`
+	RequestNum int       `json:"request"`
+	Image      string    `json:"image"`
+	TraceID    string    `json:"trace_id,omitempty"`
+	StatusCode int       `json:"status"`
+	DurationMs float64   `json:"duration_ms"`
+	BytesSent  int64     `json:"bytes"`
+	Success    bool      `json:"success"`
+	Category   string    `json:"category,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+type eventsReporter struct {
+	out    io.Writer
+	file   *os.File
+	events chan requestEvent
+	done   chan error
+}
+
+func newEventsReporter(path string) (*eventsReporter, error) {
+	reporter := &eventsReporter{
+		out:    os.Stdout,
+		events: make(chan requestEvent, 1024),
+		done:   make(chan error, 1),
+	}
+
+	if path != "-" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening events file: %v", err)
+		}
+		reporter.file = file
+		reporter.out = file
+	}
+
+	go reporter.run()
+	return reporter, nil
+}
+
+// run is the only writer, so events are never interleaved and each one is written as soon as it arrives.
+func (reporter *eventsReporter) run() {
+	encoder := json.NewEncoder(reporter.out)
+	var firstErr error
+	for event := range reporter.events {
+		if err := encoder.Encode(event); err != nil && firstErr == nil {
+			firstErr = fmt.Errorf("error writing event: %v", err)
+		}
+	}
+	reporter.done <- firstErr
+}
+
+func (reporter *eventsReporter) RecordRequest(result RequestResult) {
+	event := requestEvent{
+		Timestamp:  time.Now(),
+		RequestNum: result.RequestNum,
+		Image:      result.ImageName,
+		TraceID:    result.TraceID,
+		StatusCode: result.StatusCode,
+		DurationMs: float64(result.Duration) / float64(time.Millisecond),
+		BytesSent:  result.BytesSent,
+		Success:    result.Success,
+		Category:   result.Category,
+	}
+	if result.Err != nil {
+		event.Error = result.Err.Error()
+	}
+	reporter.events <- event
+}
+
+func (reporter *eventsReporter) Finish(summary Summary) error {
+	close(reporter.events)
+	err := <-reporter.done
+	if reporter.file != nil {
+		if closeErr := reporter.file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type imageFolder struct {
+	path   string
+	weight float64
+}
+
+// parseFolders reads -folder values. A weight is given as path=weight; a
+// path without one weighs 1, so a single plain -folder behaves as before.
+func parseFolders(values []string) ([]imageFolder, error) {
+	var folders []imageFolder
+	for _, value := range values {
+		folder := imageFolder{path: value, weight: 1}
+		if index := strings.LastIndex(value, "="); index >= 0 {
+			weight, err := strconv.ParseFloat(value[index+1:], 64)
+			if err != nil || weight <= 0 {
+				return nil, fmt.Errorf("expected path=weight with a positive weight, got %q", value)
+			}
+			folder = imageFolder{path: value[:index], weight: weight}
+		}
+		if folder.path == "" {
+			return nil, fmt.Errorf("empty folder path in %q", value)
+		}
+		folders = append(folders, folder)
+	}
+	return folders, nil
+}
+
+// loadImagesFromFolders loads every folder into one pool, tagging each image
+// with the folder it came from.
+func loadImagesFromFolders(folders []imageFolder, store *imageStore) ([]ImageFile, error) {
+	var images []ImageFile
+	for _, folder := range folders {
+		loaded, err := loadImagesFromFolder(folder.path, store)
+		if err != nil {
+			return nil, fmt.Errorf("folder %s: %v", folder.path, err)
+		}
+		if len(loaded) == 0 {
+			return nil, fmt.Errorf("folder %s has no images", folder.path)
+		}
+		for i := range loaded {
+			loaded[i].folder = folder.path
+		}
+		images = append(images, loaded...)
+	}
+	return images, nil
+}
+
+// newFolderWeightedSelector spreads each folder's weight evenly over its
+// images, so folders are picked in proportion to their weights however many
+// images each one holds.
+func newFolderWeightedSelector(images []ImageFile, folders []imageFolder, random *lockedRand) *weightedSelector {
+	weights := make(map[string]float64, len(folders))
+	counts := make(map[string]int, len(folders))
+	for _, folder := range folders {
+		weights[folder.path] = folder.weight
+	}
+	for _, image := range images {
+		counts[image.folder]++
+	}
+
+	selector := &weightedSelector{cumulative: make([]float64, len(images)), random: random}
+	total := 0.0
+	for i, image := range images {
+		total += weights[image.folder] / float64(counts[image.folder])
+		selector.cumulative[i] = total
+	}
+	return selector
+}
+
+type FolderShare struct {
+	Folder      string  `json:"folder"`
+	Images      int     `json:"images"`
+	Share       float64 `json:"share"`
+	TargetShare float64 `json:"target_share"`
+}
+
+// folderShares compares the images sent from each folder with the share its
+// weight asked for.
+func folderShares(folders []imageFolder, sent map[string]int) []FolderShare {
+	var totalWeight float64
+	var totalSent int
+	for _, folder := range folders {
+		totalWeight += folder.weight
+		totalSent += sent[folder.path]
+	}
+	shares := make([]FolderShare, 0, len(folders))
+	for _, folder := range folders {
+		share := FolderShare{Folder: folder.path, Images: sent[folder.path], TargetShare: folder.weight / totalWeight}
+		if totalSent > 0 {
+			share.Share = float64(share.Images) / float64(totalSent)
+		}
+		shares = append(shares, share)
+	}
+	sort.SliceStable(shares, func(i, j int) bool { return shares[i].TargetShare > shares[j].TargetShare })
+	return shares
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// With -forever the rolling line covers the last foreverWindow and is
+// printed every foreverReportInterval, so consecutive lines overlap and
+// show the current behavior rather than the run's average so far.
+const (
+	foreverWindow         = 10 * time.Second
+	foreverReportInterval = 5 * time.Second
+)
+
+// foreverSampleLimit bounds each set of durations a -forever run keeps for
+// its final percentiles; past it they come from a random sample.
+const foreverSampleLimit = 100_000
+
+type windowSample struct {
+	at       time.Time
+	duration time.Duration
+	success  bool
+	failure  bool
+}
+
+// slidingWindow keeps the outcomes of requests completed within span.
+type slidingWindow struct {
+	span    time.Duration
+	mutex   sync.Mutex
+	samples []windowSample
+}
+
+func newSlidingWindow(span time.Duration) *slidingWindow {
+	return &slidingWindow{span: span}
+}
+
+func (window *slidingWindow) record(result RequestResult) {
+	window.mutex.Lock()
+	defer window.mutex.Unlock()
+	window.samples = append(window.samples, windowSample{
+		at:       time.Now(),
+		duration: result.Duration,
+		success:  result.Success,
+		failure:  !result.Success && !result.Neutral,
+	})
+}
+
+// snapshot drops samples older than span and totals the rest in the shape
+// the soak reporter formats.
+func (window *slidingWindow) snapshot(now time.Time) soakSnapshot {
+	window.mutex.Lock()
+	defer window.mutex.Unlock()
+
+	cutoff := now.Add(-window.span)
+	first := 0
+	for first < len(window.samples) && window.samples[first].at.Before(cutoff) {
+		first++
+	}
+	window.samples = append(window.samples[:0], window.samples[first:]...)
+
+	var snapshot soakSnapshot
+	for _, sample := range window.samples {
+		snapshot.completed++
+		if sample.success {
+			snapshot.successes++
+			snapshot.totalTime += sample.duration
+		}
+		if sample.failure {
+			snapshot.failures++
+		}
+	}
+	return snapshot
+}
+
+// startForeverReporter prints the sliding window next to the cumulative
+// totals until the returned function is called.
+func startForeverReporter(window *slidingWindow, stats *RequestStats, startTime time.Time) func() {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(foreverReportInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			now := time.Now()
+			elapsed := now.Sub(startTime)
+			// Early on the window isn't full yet; rates use the time covered.
+			fmt.Printf("[forever %v] last %v: %s | total: %s\n", elapsed.Round(time.Second), window.span,
+				formatSoakWindow(soakSnapshot{}, window.snapshot(now), min(window.span, elapsed)),
+				formatSoakWindow(soakSnapshot{}, takeSoakSnapshot(stats), elapsed))
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
+
+// watchInterrupt calls onInterrupt on the first SIGINT or SIGTERM so the run
+// stops sending and drains. A second signal gets the default behavior and
+// kills the process without waiting for requests in flight.
+func watchInterrupt(onInterrupt func()) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		signal.Stop(signals)
+		onInterrupt()
+	}()
+}
@@ -0,0 +1,190 @@
+//go:build grpc
+
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+	"unicode"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const grpcSupported = true
+
+// grpcChunkSize keeps every stream message well below gRPC's default 4 MB
+// receive limit.
+const grpcChunkSize = 64 << 10
+
+// grpcUploader streams each image over a client-streaming call to
+// -grpc-method and waits for the single reply. No generated client is
+// needed: messages are encoded by hand as
+//
+//	message UploadChunk {
+//	  string name = 1;         // first message only
+//	  string content_type = 2; // first message only
+//	  bytes data = 3;
+//	}
+//
+// and the reply is read but not decoded. All workers share one connection,
+// which HTTP/2 multiplexes.
+type grpcUploader struct {
+	cfg         *Config
+	bearerToken string
+	conn        *grpc.ClientConn
+}
+
+func newGRPCUploader(cfg *Config, bearerToken string) (*grpcUploader, error) {
+	target, err := url.Parse(cfg.URL)
+	if err != nil {
+		return nil, err
+	}
+	creds := insecure.NewCredentials()
+	if target.Scheme == "grpcs" {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.NewClient(target.Host, grpc.WithTransportCredentials(creds), grpc.WithUserAgent(cfg.UserAgent))
+	if err != nil {
+		return nil, fmt.Errorf("error creating gRPC client: %v", err)
+	}
+	return &grpcUploader{cfg: cfg, bearerToken: bearerToken, conn: conn}, nil
+}
+
+func (uploader *grpcUploader) makeRequest(ctx context.Context, job uploadJob) RequestResult {
+	result := RequestResult{RequestNum: job.requestNum, ImageName: job.image.name, TraceID: job.traceID, Images: 1, PayloadBytes: int64(len(job.image.data))}
+
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+	ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+uploader.bearerToken)
+	if job.traceID != "" {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(uploader.cfg.TraceHeader), job.traceID)
+	}
+
+	startTime := time.Now()
+	sent, err := uploader.stream(ctx, job, &result)
+	result.Duration = time.Since(startTime)
+	result.BytesSent = sent
+	if err != nil {
+		result.Category = grpcCategory(err)
+		if ctx.Err() != nil && result.Category != categoryTimeout {
+			result.Category, result.Neutral = categoryCanceled, true
+		}
+		fmt.Printf("%s failed: %v\n", job.label(), err)
+		result.Err = err
+		return result
+	}
+
+	result.Success = true
+	if job.requestNum%50 == 0 {
+		fmt.Printf("%s acknowledged in %v\n", job.label(), result.Duration)
+	}
+	return result
+}
+
+// stream sends the chunks and reads the reply, filling in the upload and
+// server times. A send that fails with io.EOF means the server ended the
+// call; its status comes from RecvMsg.
+func (uploader *grpcUploader) stream(ctx context.Context, job uploadJob, result *RequestResult) (int64, error) {
+	startTime := time.Now()
+	stream, err := uploader.conn.NewStream(ctx, &grpc.StreamDesc{ClientStreams: true}, uploader.cfg.GRPCMethod, grpc.ForceCodec(rawCodec{}))
+	if err != nil {
+		return 0, err
+	}
+
+	var sent int64
+	data := job.image.data
+	header := appendProtoBytes(nil, 1, []byte(job.image.name))
+	header = appendProtoBytes(header, 2, []byte(job.image.contentType))
+	for first := true; first || len(data) > 0; first = false {
+		chunk := data[:min(grpcChunkSize, len(data))]
+		data = data[len(chunk):]
+		message := appendProtoBytes(nil, 3, chunk)
+		if first {
+			message = append(header, message...)
+		}
+		if err = stream.SendMsg(message); err != nil {
+			break
+		}
+		sent += int64(len(chunk))
+	}
+	if err == nil {
+		err = stream.CloseSend()
+	}
+	if err != nil && err != io.EOF {
+		return sent, err
+	}
+	sentAt := time.Now()
+	result.UploadTime = sentAt.Sub(startTime)
+
+	var reply []byte
+	err = stream.RecvMsg(&reply)
+	result.ServerTime = time.Since(sentAt)
+	return sent, err
+}
+
+func (uploader *grpcUploader) close() {
+	uploader.conn.Close()
+}
+
+// grpcCategory maps a call's status to a failure category such as
+// grpc_invalid_argument; deadlines and unreachable servers use the same
+// categories as HTTP.
+func grpcCategory(err error) string {
+	switch code := status.Code(err); code {
+	case codes.DeadlineExceeded:
+		return categoryTimeout
+	case codes.Unavailable:
+		return categoryConnection
+	default:
+		var name strings.Builder
+		name.WriteString("grpc_")
+		for i, r := range code.String() {
+			if unicode.IsUpper(r) && i > 0 {
+				name.WriteByte('_')
+			}
+			name.WriteRune(unicode.ToLower(r))
+		}
+		return name.String()
+	}
+}
+
+// appendProtoBytes appends a length-delimited protobuf field.
+func appendProtoBytes(message []byte, field int, value []byte) []byte {
+	message = binary.AppendUvarint(message, uint64(field)<<3|2)
+	message = binary.AppendUvarint(message, uint64(len(value)))
+	return append(message, value...)
+}
+
+// rawCodec passes pre-encoded messages through. It is named proto so the
+// server decodes them with its protobuf codec.
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	message, ok := v.([]byte)
+	if !ok {
+		return nil, fmt.Errorf("raw codec cannot marshal %T", v)
+	}
+	return message, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	message, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("raw codec cannot unmarshal into %T", v)
+	}
+	*message = append((*message)[:0], data...)
+	return nil
+}
+
+func (rawCodec) Name() string { return "proto" }
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net/url"
+	"sort"
+	"sync"
+	"time"
+)
+
+type HostConcurrency struct {
+	Host    string  `json:"host"`
+	Limit   int     `json:"limit"`
+	Average float64 `json:"average"`
+	Max     int     `json:"max"`
+}
+
+type hostUsage struct {
+	slots      chan struct{}
+	inFlight   int
+	max        int
+	busy       float64 // request-seconds, for the time-weighted average
+	lastChange time.Time
+}
+
+// hostLimiter caps the requests in flight to each target host, keyed by the
+// URL's host:port, so one backend can't take the whole pool while the pool
+// size still bounds the total.
+type hostLimiter struct {
+	limit   int
+	started time.Time
+	mutex   sync.Mutex
+	hosts   map[string]*hostUsage
+}
+
+func newHostLimiter(limit int) *hostLimiter {
+	return &hostLimiter{limit: limit, started: time.Now(), hosts: make(map[string]*hostUsage)}
+}
+
+func (limiter *hostLimiter) usage(host string) *hostUsage {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	usage := limiter.hosts[host]
+	if usage == nil {
+		usage = &hostUsage{slots: make(chan struct{}, limiter.limit), lastChange: time.Now()}
+		limiter.hosts[host] = usage
+	}
+	return usage
+}
+
+// change moves a host's in-flight count by delta, first crediting the time
+// spent at the old count.
+func (limiter *hostLimiter) change(usage *hostUsage, delta int) {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	now := time.Now()
+	usage.busy += float64(usage.inFlight) * now.Sub(usage.lastChange).Seconds()
+	usage.lastChange = now
+	usage.inFlight += delta
+	usage.max = max(usage.max, usage.inFlight)
+}
+
+// acquire waits for a slot on the target's host and returns the function
+// that frees it, or the context's error if the run ends first.
+func (limiter *hostLimiter) acquire(ctx context.Context, target string) (func(), error) {
+	host := target
+	if parsed, err := url.Parse(target); err == nil {
+		host = parsed.Host
+	}
+	usage := limiter.usage(host)
+	select {
+	case usage.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	limiter.change(usage, 1)
+	return func() {
+		limiter.change(usage, -1)
+		<-usage.slots
+	}, nil
+}
+
+func (limiter *hostLimiter) report() []HostConcurrency {
+	limiter.mutex.Lock()
+	defer limiter.mutex.Unlock()
+	now := time.Now()
+	elapsed := now.Sub(limiter.started).Seconds()
+	report := make([]HostConcurrency, 0, len(limiter.hosts))
+	for host, usage := range limiter.hosts {
+		busy := usage.busy + float64(usage.inFlight)*now.Sub(usage.lastChange).Seconds()
+		entry := HostConcurrency{Host: host, Limit: limiter.limit, Max: usage.max}
+		if elapsed > 0 {
+			entry.Average = busy / elapsed
+		}
+		report = append(report, entry)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Host < report[j].Host })
+	return report
+}
@@ -0,0 +1,40 @@
+package main
+
+import "time"
+
+// checkInterval is how often a monitor looks for progress against timeout.
+// The floor keeps NewTicker from panicking on timeouts of a few nanoseconds.
+func checkInterval(timeout time.Duration) time.Duration {
+	return max(min(timeout/4, time.Second), time.Millisecond)
+}
+
+// startIdleMonitor calls onIdle once if no request completes for timeout.
+func startIdleMonitor(pool *workerPool, timeout time.Duration, onIdle func()) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(checkInterval(timeout))
+		defer ticker.Stop()
+
+		lastCompleted := pool.completedCount()
+		lastProgress := time.Now()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			if completed := pool.completedCount(); completed != lastCompleted {
+				lastCompleted = completed
+				lastProgress = time.Now()
+				continue
+			}
+			if time.Since(lastProgress) >= timeout {
+				onIdle()
+				return
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckInterval(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    time.Duration
+	}{
+		{timeout: time.Nanosecond, want: time.Millisecond},
+		{timeout: 3 * time.Nanosecond, want: time.Millisecond},
+		{timeout: 2 * time.Millisecond, want: time.Millisecond},
+		{timeout: 2 * time.Second, want: 500 * time.Millisecond},
+		{timeout: time.Minute, want: time.Second},
+	}
+	for _, test := range tests {
+		if got := checkInterval(test.timeout); got != test.want {
+			t.Errorf("checkInterval(%v) = %v, want %v", test.timeout, got, test.want)
+		}
+	}
+}
+
+func TestIdleMonitorTinyTimeout(t *testing.T) {
+	pool := newWorkerPool(1, 0, func(workerID int, requestNum int) {})
+	defer pool.wait()
+
+	idle := make(chan struct{})
+	stop := startIdleMonitor(pool, time.Nanosecond, func() { close(idle) })
+	defer stop()
+	select {
+	case <-idle:
+	case <-time.After(5 * time.Second):
+		t.Fatal("idle monitor never fired")
+	}
+}
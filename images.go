@@ -0,0 +1,59 @@
+package main
+
+import "sort"
+
+type ImageFailureRate struct {
+	Image       string  `json:"image"`
+	Requests    int     `json:"requests"`
+	Failures    int     `json:"failures"`
+	FailureRate float64 `json:"failure_rate"`
+}
+
+type imageOutcomes struct {
+	requests int
+	failures int
+}
+
+const (
+	// problemImageMinRequests keeps one unlucky request from flagging an image.
+	problemImageMinRequests = 3
+	// problemImageFactor is how many times the overall failure rate an image
+	// must reach to stand out.
+	problemImageFactor = 2
+	problemImageLimit  = 10
+)
+
+// problemImages lists the images that fail notably more often than the run
+// as a whole, worst first.
+func problemImages(outcomes map[string]*imageOutcomes) []ImageFailureRate {
+	var requests, failures int
+	for _, outcome := range outcomes {
+		requests += outcome.requests
+		failures += outcome.failures
+	}
+	if failures == 0 {
+		return nil
+	}
+	overall := float64(failures) / float64(requests)
+
+	var problems []ImageFailureRate
+	for name, outcome := range outcomes {
+		if outcome.requests < problemImageMinRequests || outcome.failures < 2 {
+			continue
+		}
+		rate := float64(outcome.failures) / float64(outcome.requests)
+		if rate >= problemImageFactor*overall {
+			problems = append(problems, ImageFailureRate{Image: name, Requests: outcome.requests, Failures: outcome.failures, FailureRate: rate})
+		}
+	}
+	sort.Slice(problems, func(i, j int) bool {
+		if problems[i].FailureRate != problems[j].FailureRate {
+			return problems[i].FailureRate > problems[j].FailureRate
+		}
+		return problems[i].Image < problems[j].Image
+	})
+	if len(problems) > problemImageLimit {
+		problems = problems[:problemImageLimit]
+	}
+	return problems
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestProblemImages(t *testing.T) {
+	many := make(map[string]*imageOutcomes)
+	for i := range 12 {
+		many[fmt.Sprintf("bad%02d.jpg", i)] = &imageOutcomes{requests: 4, failures: 2 + i%3}
+	}
+	for i := range 40 {
+		many[fmt.Sprintf("good%02d.jpg", i)] = &imageOutcomes{requests: 10}
+	}
+
+	tests := []struct {
+		name     string
+		outcomes map[string]*imageOutcomes
+		want     []string
+	}{
+		{name: "no images", outcomes: nil},
+		{name: "no failures", outcomes: map[string]*imageOutcomes{"a.jpg": {requests: 10}, "b.jpg": {requests: 10}}},
+		{name: "one image stands out", outcomes: map[string]*imageOutcomes{
+			"a.jpg": {requests: 10, failures: 1},
+			"b.jpg": {requests: 10, failures: 1},
+			"c.jpg": {requests: 10, failures: 6},
+		}, want: []string{"c.jpg"}},
+		{name: "worst first, ties by name", outcomes: map[string]*imageOutcomes{
+			"a.jpg": {requests: 20},
+			"b.jpg": {requests: 20},
+			"c.jpg": {requests: 4, failures: 2},
+			"d.jpg": {requests: 4, failures: 4},
+			"e.jpg": {requests: 4, failures: 2},
+		}, want: []string{"d.jpg", "c.jpg", "e.jpg"}},
+		{name: "too few requests", outcomes: map[string]*imageOutcomes{
+			"a.jpg": {requests: 20},
+			"b.jpg": {requests: 2, failures: 2},
+		}},
+		{name: "a single failure", outcomes: map[string]*imageOutcomes{
+			"a.jpg": {requests: 50},
+			"b.jpg": {requests: 3, failures: 1},
+		}},
+		{name: "everything fails alike", outcomes: map[string]*imageOutcomes{
+			"a.jpg": {requests: 10, failures: 5},
+			"b.jpg": {requests: 10, failures: 5},
+		}},
+		{name: "limited", outcomes: many, want: []string{
+			"bad02.jpg", "bad05.jpg", "bad08.jpg", "bad11.jpg",
+			"bad01.jpg", "bad04.jpg", "bad07.jpg", "bad10.jpg",
+			"bad00.jpg", "bad03.jpg",
+		}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			var got []string
+			for _, problem := range problemImages(test.outcomes) {
+				got = append(got, problem.Image)
+				outcome := test.outcomes[problem.Image]
+				if problem.Requests != outcome.requests || problem.Failures != outcome.failures ||
+					problem.FailureRate != float64(outcome.failures)/float64(outcome.requests) {
+					t.Errorf("%s reported as %+v, want %+v", problem.Image, problem, *outcome)
+				}
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("problemImages = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+// TestSummaryProblemImages checks the summary counts outcomes per image,
+// leaving out neutral results.
+func TestSummaryProblemImages(t *testing.T) {
+	stats := &RequestStats{}
+	for i := range 30 {
+		image := fmt.Sprintf("%d.jpg", i%3)
+		result := RequestResult{RequestNum: i, ImageName: image, Success: true, Duration: time.Millisecond}
+		if image == "2.jpg" && i%2 == 0 {
+			result = RequestResult{RequestNum: i, ImageName: image, Category: "5xx", Err: fmt.Errorf("status 500")}
+		}
+		if image == "1.jpg" && i%2 == 0 {
+			result = RequestResult{RequestNum: i, ImageName: image, Category: categoryCanceled, Neutral: true}
+		}
+		stats.record(result)
+	}
+
+	problems := stats.summary(30, time.Second).ProblemImages
+	if len(problems) != 1 || problems[0].Image != "2.jpg" || problems[0].Requests != 10 || problems[0].Failures != 5 {
+		t.Errorf("problem images = %+v, want 2.jpg with 5 of 10 failed", problems)
+	}
+}
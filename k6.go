@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// k6Reporter writes -json in the shape of k6's end-of-test summary (the
+// data handed to handleSummary), so dashboards built for k6 can read it.
+// The mapping is approximate:
+//
+//   - http_req_duration: trend in milliseconds over every request that got
+//     a response; k6 measures the same span from sending to the last byte.
+//   - http_reqs and iterations: counters of requests sent, one iteration
+//     per upload.
+//   - http_req_failed: rate of failed requests; neutral outcomes such as 304
+//     with -conditional count as passed, like an expected status in k6.
+//   - data_sent: bytes of request bodies only, without headers.
+//   - vus and vus_max: the average and configured worker counts.
+//
+// Like the console percentiles, the trend comes from a bounded sample when
+// sampleLimit is set (-forever); min, max and avg still cover every request.
+type k6Reporter struct {
+	path        string
+	sampleLimit int
+	mutex       sync.Mutex
+	durations   durationSample
+	fastest     time.Duration
+	slowest     time.Duration
+	failed      int
+	requests    int
+}
+
+type k6Metric struct {
+	Type     string             `json:"type"`
+	Contains string             `json:"contains"`
+	Values   map[string]float64 `json:"values"`
+}
+
+type k6Summary struct {
+	Options   map[string]any      `json:"options"`
+	State     map[string]any      `json:"state"`
+	Metrics   map[string]k6Metric `json:"metrics"`
+	RootGroup map[string]any      `json:"root_group"`
+}
+
+func (reporter *k6Reporter) RecordRequest(result RequestResult) {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.requests++
+	if !result.Success && !result.Neutral {
+		reporter.failed++
+	}
+	if result.StatusCode > 0 {
+		if reporter.durations.count == 0 || result.Duration < reporter.fastest {
+			reporter.fastest = result.Duration
+		}
+		reporter.slowest = max(reporter.slowest, result.Duration)
+		reporter.durations.add(result.Duration, reporter.sampleLimit)
+	}
+}
+
+func (reporter *k6Reporter) Finish(summary Summary) error {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	seconds := summary.TotalDuration.Seconds()
+	metrics := map[string]k6Metric{
+		"http_reqs":  counterMetric("default", float64(reporter.requests), seconds),
+		"iterations": counterMetric("default", float64(reporter.requests), seconds),
+		"data_sent":  counterMetric("data", float64(summary.BytesSent), seconds),
+		"vus": {Type: "gauge", Contains: "default", Values: map[string]float64{
+			"value": summary.AverageConcurrency, "min": 0, "max": float64(summary.MaxConcurrency),
+		}},
+		"vus_max": {Type: "gauge", Contains: "default", Values: map[string]float64{
+			"value": float64(summary.ConfiguredConcurrency), "min": float64(summary.ConfiguredConcurrency), "max": float64(summary.ConfiguredConcurrency),
+		}},
+	}
+	if reporter.requests > 0 {
+		// In k6 "passes" counts true samples, i.e. failed requests.
+		metrics["http_req_failed"] = k6Metric{Type: "rate", Contains: "default", Values: map[string]float64{
+			"rate":   float64(reporter.failed) / float64(reporter.requests),
+			"passes": float64(reporter.failed),
+			"fails":  float64(reporter.requests - reporter.failed),
+		}}
+	}
+	if reporter.durations.count > 0 {
+		metrics["http_req_duration"] = reporter.trendMetric()
+	}
+
+	options := map[string]any{
+		"summaryTrendStats": []string{"avg", "min", "med", "max", "p(90)", "p(95)"},
+		"summaryTimeUnit":   "",
+		"noColor":           false,
+	}
+	if len(summary.Labels) > 0 {
+		// Test-wide tags, as k6 takes them from its tags option.
+		options["tags"] = summary.Labels
+	}
+
+	data, err := json.MarshalIndent(k6Summary{
+		Options: options,
+		State: map[string]any{
+			"isStdOutTTY":       false,
+			"isStdErrTTY":       false,
+			"testRunDurationMs": float64(summary.TotalDuration) / float64(time.Millisecond),
+		},
+		Metrics: metrics,
+		RootGroup: map[string]any{
+			"name": "", "path": "", "id": "d41d8cd98f00b204e9800998ecf8427e",
+			"groups": []any{}, "checks": []any{},
+		},
+	}, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding k6 summary: %v", err)
+	}
+	if err := os.WriteFile(reporter.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing k6 summary: %v", err)
+	}
+	return nil
+}
+
+func counterMetric(contains string, count float64, seconds float64) k6Metric {
+	return k6Metric{Type: "counter", Contains: contains, Values: map[string]float64{"count": count, "rate": count / seconds}}
+}
+
+func (reporter *k6Reporter) trendMetric() k6Metric {
+	milliseconds := func(duration time.Duration) float64 {
+		return float64(duration) / float64(time.Millisecond)
+	}
+	marks := percentiles(reporter.durations.values, 50, 90, 95)
+	return k6Metric{Type: "trend", Contains: "time", Values: map[string]float64{
+		"avg":   milliseconds(reporter.durations.average()),
+		"min":   milliseconds(reporter.fastest),
+		"med":   milliseconds(marks[0]),
+		"max":   milliseconds(reporter.slowest),
+		"p(90)": milliseconds(marks[1]),
+		"p(95)": milliseconds(marks[2]),
+	}}
+}
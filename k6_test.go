@@ -0,0 +1,55 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestK6ReporterTrend(t *testing.T) {
+	tests := []struct {
+		name        string
+		sampleLimit int
+	}{
+		{name: "every duration", sampleLimit: 0},
+		{name: "-forever sample", sampleLimit: 50},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "summary.json")
+			reporter := &k6Reporter{path: path, sampleLimit: test.sampleLimit}
+			for i := 1; i <= 1000; i++ {
+				reporter.RecordRequest(RequestResult{StatusCode: 200, Success: true, Duration: time.Duration(i) * time.Millisecond})
+			}
+			reporter.RecordRequest(RequestResult{Category: categoryConnection})
+
+			if test.sampleLimit > 0 && len(reporter.durations.values) > test.sampleLimit {
+				t.Errorf("kept %d durations, want at most %d", len(reporter.durations.values), test.sampleLimit)
+			}
+			if err := reporter.Finish(Summary{TotalDuration: time.Second}); err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var summary k6Summary
+			if err := json.Unmarshal(data, &summary); err != nil {
+				t.Fatal(err)
+			}
+
+			trend := summary.Metrics["http_req_duration"].Values
+			if trend["min"] != 1 || trend["max"] != 1000 || trend["avg"] != 500.5 {
+				t.Errorf("min %v, max %v, avg %v; want 1, 1000 and 500.5 over every request", trend["min"], trend["max"], trend["avg"])
+			}
+			if med := trend["med"]; med < 1 || med > 1000 {
+				t.Errorf("med = %v, outside the recorded durations", med)
+			}
+			if reqs := summary.Metrics["http_reqs"].Values["count"]; reqs != 1001 {
+				t.Errorf("http_reqs = %v, want 1001", reqs)
+			}
+		})
+	}
+}
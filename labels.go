@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+var prometheusLabelName = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*$`)
+
+// reservedLabels are label names the OpenMetrics output already uses on its
+// own series; a run label with the same name would make them ambiguous.
+var reservedLabels = []string{"category", "config_hash", "hostname", "le", "result", "stage", "tool_version"}
+
+// parseLabels reads -label key=value pairs. With -openmetrics the keys must
+// also be valid Prometheus label names, since they become labels of every
+// series there.
+func parseLabels(values []string, prometheus bool) (map[string]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	labels := make(map[string]string, len(values))
+	for _, value := range values {
+		key, labelValue, ok := strings.Cut(value, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("expected key=value, got %q", value)
+		}
+		if _, seen := labels[key]; seen {
+			return nil, fmt.Errorf("label %q is given more than once", key)
+		}
+		if prometheus {
+			if !prometheusLabelName.MatchString(key) || strings.HasPrefix(key, "__") {
+				return nil, fmt.Errorf("label %q is not a valid Prometheus label name", key)
+			}
+			if slices.Contains(reservedLabels, key) {
+				return nil, fmt.Errorf("label %q clashes with a label of the OpenMetrics output", key)
+			}
+		}
+		labels[key] = labelValue
+	}
+	return labels, nil
+}
+
+// labelKeys returns the keys in a stable order for column and label lists.
+func labelKeys(labels map[string]string) []string {
+	return slices.Sorted(maps.Keys(labels))
+}
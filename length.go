@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const categoryLength = "length"
+
+// lengthRange bounds the response body length for -expect-length; a
+// negative max means no upper bound.
+type lengthRange struct {
+	min int64
+	max int64
+}
+
+// parseLengthRange reads min:max, where either side may be empty, or a
+// single number for an exact length.
+func parseLengthRange(value string) (*lengthRange, error) {
+	minText, maxText, isRange := strings.Cut(value, ":")
+	if !isRange {
+		maxText = minText
+	}
+
+	bounds := &lengthRange{max: -1}
+	if minText != "" {
+		parsed, err := strconv.ParseInt(minText, 10, 64)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid minimum %q", minText)
+		}
+		bounds.min = parsed
+	}
+	if maxText != "" {
+		parsed, err := strconv.ParseInt(maxText, 10, 64)
+		if err != nil || parsed < bounds.min {
+			return nil, fmt.Errorf("invalid maximum %q", maxText)
+		}
+		bounds.max = parsed
+	}
+	return bounds, nil
+}
+
+func (bounds *lengthRange) contains(length int64) bool {
+	return length >= bounds.min && (bounds.max < 0 || length <= bounds.max)
+}
+
+func (bounds *lengthRange) String() string {
+	if bounds.max < 0 {
+		return fmt.Sprintf("at least %d bytes", bounds.min)
+	}
+	if bounds.min == bounds.max {
+		return fmt.Sprintf("%d bytes", bounds.min)
+	}
+	return fmt.Sprintf("%d-%d bytes", bounds.min, bounds.max)
+}
@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testConfig has the defaults parseConfig would fill in for a plain
+// multipart upload.
+func testConfig(t *testing.T, successCodes string) *Config {
+	t.Helper()
+	codes, err := parseStatusCodes(successCodes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &Config{
+		FileField:     "file[]",
+		UserAgent:     "uploader-test",
+		MaxBodyBuffer: 1 << 20,
+		successCodes:  codes,
+	}
+}
+
+func testJob(url string) uploadJob {
+	return uploadJob{
+		url:   url,
+		image: ImageFile{name: "face.jpg", data: []byte("\xff\xd8\xff\xe0 not really a jpeg"), contentType: "image/jpeg"},
+	}
+}
+
+// statusServer answers every request with status.
+func statusServer(t *testing.T, status int) *httptest.Server {
+	t.Helper()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+type receivedPart struct {
+	field    string
+	filename string
+	data     string
+}
+
+// multipartServer records the parts of the last request it received: file
+// parts with their filenames, and plain fields in values.
+func multipartServer(t *testing.T) (*httptest.Server, *[]receivedPart, *map[string][]string) {
+	t.Helper()
+	var mutex sync.Mutex
+	var parts []receivedPart
+	var values map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		mutex.Lock()
+		defer mutex.Unlock()
+		parts, values = nil, r.MultipartForm.Value
+		for field, headers := range r.MultipartForm.File {
+			for _, header := range headers {
+				file, err := header.Open()
+				if err != nil {
+					http.Error(w, err.Error(), http.StatusBadRequest)
+					return
+				}
+				data, _ := io.ReadAll(file)
+				file.Close()
+				parts = append(parts, receivedPart{field: field, filename: header.Filename, data: string(data)})
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &parts, &values
+}
+
+func TestMakeRequestSuccessCodes(t *testing.T) {
+	tests := []struct {
+		name         string
+		successCodes string
+		status       int
+		wantSuccess  bool
+		wantCategory string
+	}{
+		{name: "default 200", successCodes: "200", status: http.StatusOK, wantSuccess: true},
+		{name: "default rejects 201", successCodes: "200", status: http.StatusCreated, wantCategory: "2xx"},
+		{name: "201 accepted", successCodes: "200,201,202", status: http.StatusCreated, wantSuccess: true},
+		{name: "202 accepted", successCodes: "200,201,202", status: http.StatusAccepted, wantSuccess: true},
+		{name: "204 outside the set", successCodes: "200,201,202", status: http.StatusNoContent, wantCategory: "2xx"},
+		{name: "client error", successCodes: "200,201,202", status: http.StatusNotFound, wantCategory: "4xx"},
+		{name: "server error", successCodes: "200,201,202", status: http.StatusInternalServerError, wantCategory: "5xx"},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			server := statusServer(t, test.status)
+			cfg := testConfig(t, test.successCodes)
+
+			result := makeRequest(context.Background(), cfg, server.Client(), testJob(server.URL), "token")
+			if result.StatusCode != test.status {
+				t.Fatalf("status = %d, want %d", result.StatusCode, test.status)
+			}
+			if result.Success != test.wantSuccess || result.Category != test.wantCategory {
+				t.Errorf("success = %v, category = %q; want %v, %q", result.Success, result.Category, test.wantSuccess, test.wantCategory)
+			}
+			if !test.wantSuccess && result.Err == nil {
+				t.Errorf("failed request has no error")
+			}
+		})
+	}
+}
+
+func TestSafeUploadRecoversPanics(t *testing.T) {
+	tests := []struct {
+		name         string
+		upload       func() RequestResult
+		wantCategory string
+		wantSuccess  bool
+	}{
+		{name: "no panic", upload: func() RequestResult { return RequestResult{Success: true} }, wantSuccess: true},
+		{name: "panic with a string", upload: func() RequestResult { panic("boom") }, wantCategory: categoryPanic},
+		{name: "panic with an error", upload: func() RequestResult { panic(errors.New("boom")) }, wantCategory: categoryPanic},
+		{name: "runtime error", upload: func() RequestResult {
+			var image *ImageFile
+			return RequestResult{ImageName: image.name}
+		}, wantCategory: categoryPanic},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			job := testJob("http://example.test/")
+			job.requestNum = 7
+			result := safeUpload(job, test.upload)
+			if result.Success != test.wantSuccess || result.Category != test.wantCategory {
+				t.Fatalf("success = %v, category = %q; want %v, %q", result.Success, result.Category, test.wantSuccess, test.wantCategory)
+			}
+			if test.wantCategory == categoryPanic && (result.Err == nil || result.RequestNum != 7 || result.ImageName != job.image.name) {
+				t.Errorf("panicked result = %+v, want the job's request and image with an error", result)
+			}
+		})
+	}
+}
+
+// TestRunSurvivesPanickingRequests drives the worker pool the way main does,
+// with every third request panicking, and checks the run still drains and
+// the summary counts the panics as failures.
+func TestRunSurvivesPanickingRequests(t *testing.T) {
+	const requests = 30
+	stats := &RequestStats{}
+	pool := newWorkerPool(4, 0, func(workerID int, requestNum int) {
+		job := testJob("http://example.test/")
+		job.requestNum = requestNum
+		stats.record(safeUpload(job, func() RequestResult {
+			if requestNum%3 == 0 {
+				panic(fmt.Sprintf("request %d", requestNum))
+			}
+			return RequestResult{RequestNum: requestNum, Success: true, Duration: time.Millisecond}
+		}))
+	})
+	for i := range requests {
+		pool.submit(i)
+	}
+	pool.wait()
+
+	summary := stats.summary(requests, time.Second)
+	if summary.SuccessCount != 20 || summary.FailureCount != 10 || summary.FailureCategories[categoryPanic] != 10 {
+		t.Errorf("summary has %d successes, %d failures, categories %v; want 20, 10 and 10 panics",
+			summary.SuccessCount, summary.FailureCount, summary.FailureCategories)
+	}
+}
+
+func TestMakeRequestSendsFormFields(t *testing.T) {
+	server, parts, values := multipartServer(t)
+	cfg := testConfig(t, "200")
+	cfg.formFields = []formField{{key: "faceListId", value: "1"}, {key: "label", value: "run 7"}}
+
+	job := testJob(server.URL)
+	job.formFields = []formField{{key: "label", value: "per request"}}
+	result := makeRequest(context.Background(), cfg, server.Client(), job, "token")
+	if !result.Success {
+		t.Fatalf("upload failed: status %d, %v", result.StatusCode, result.Err)
+	}
+
+	if len(*parts) != 1 || (*parts)[0].field != "file[]" || (*parts)[0].data != string(job.image.data) {
+		t.Errorf("file parts = %+v, want the image as file[]", *parts)
+	}
+	want := map[string][]string{"faceListId": {"1"}, "label": {"run 7", "per request"}}
+	for key, wantValues := range want {
+		if got := (*values)[key]; !slices.Equal(got, wantValues) {
+			t.Errorf("field %s = %q, want %q", key, got, wantValues)
+		}
+	}
+}
+
+func TestMakeRequestSendsNamedParts(t *testing.T) {
+	server, parts, _ := multipartServer(t)
+	cfg := testConfig(t, "200")
+	cfg.parts = []namedPart{
+		{field: "mask", image: ImageFile{name: "mask.png", data: []byte("mask")}},
+		{field: "metadata", image: ImageFile{name: "meta.json", data: []byte(`{"id":1}`), contentType: "application/json"}},
+	}
+
+	job := testJob(server.URL)
+	result := makeRequest(context.Background(), cfg, server.Client(), job, "token")
+	if !result.Success {
+		t.Fatalf("upload failed: status %d, %v", result.StatusCode, result.Err)
+	}
+
+	want := []receivedPart{
+		{field: "file[]", filename: "face.jpg", data: string(job.image.data)},
+		{field: "mask", filename: "mask.png", data: "mask"},
+		{field: "metadata", filename: "meta.json", data: `{"id":1}`},
+	}
+	got := slices.SortedFunc(slices.Values(*parts), func(a, b receivedPart) int { return strings.Compare(a.field, b.field) })
+	if !slices.Equal(got, want) {
+		t.Errorf("parts = %+v, want %+v", got, want)
+	}
+}
+
+func TestFileDisposition(t *testing.T) {
+	tests := []struct {
+		field    string
+		filename string
+		want     string
+	}{
+		{field: "file[]", filename: "face.jpg", want: `form-data; name="file[]"; filename="face.jpg"`},
+		{field: "file[]", filename: `say "cheese".jpg`, want: `form-data; name="file[]"; filename="say \"cheese\".jpg"`},
+		{field: "file[]", filename: `dir\face.jpg`, want: `form-data; name="file[]"; filename="dir\\face.jpg"`},
+		{field: "file[]", filename: "лицо.jpg", want: `form-data; name="file[]"; filename="____.jpg"; filename*=UTF-8''%D0%BB%D0%B8%D1%86%D0%BE.jpg`},
+		{field: "file[]", filename: "tab\there.jpg", want: `form-data; name="file[]"; filename="tab_here.jpg"; filename*=UTF-8''tab%09here.jpg`},
+	}
+	for _, test := range tests {
+		if got := fileDisposition(test.field, test.filename); got != test.want {
+			t.Errorf("fileDisposition(%q, %q) = %s, want %s", test.field, test.filename, got, test.want)
+		}
+	}
+}
+
+func TestMakeRequestFilenamesSurviveParsing(t *testing.T) {
+	for _, filename := range []string{"face.jpg", `say "cheese".jpg`, "лицо 1.jpg", "顔.jpg"} {
+		t.Run(filename, func(t *testing.T) {
+			server, parts, _ := multipartServer(t)
+			job := testJob(server.URL)
+			job.image.name = filename
+
+			result := makeRequest(context.Background(), testConfig(t, "200"), server.Client(), job, "token")
+			if !result.Success {
+				t.Fatalf("upload failed: status %d, %v", result.StatusCode, result.Err)
+			}
+			if len(*parts) != 1 || (*parts)[0].filename != filename {
+				t.Errorf("server saw parts %+v, want one named %q", *parts, filename)
+			}
+		})
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestTransportErrorCategory(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{name: "canceled", err: context.Canceled, want: categoryCanceled},
+		{name: "wrapped cancel", err: fmt.Errorf("Post: %w", context.Canceled), want: categoryCanceled},
+		{name: "deadline", err: context.DeadlineExceeded, want: categoryTimeout},
+		{name: "net timeout", err: &net.OpError{Op: "read", Net: "tcp", Err: timeoutError{}}, want: categoryTimeout},
+		{name: "refused", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: categoryConnection},
+		{name: "other", err: io.ErrUnexpectedEOF, want: categoryConnection},
+	}
+	for _, test := range tests {
+		if got := transportErrorCategory(test.err); got != test.want {
+			t.Errorf("%s: transportErrorCategory(%v) = %q, want %q", test.name, test.err, got, test.want)
+		}
+	}
+}
+
+// TestMakeRequestCanceledIsNeutral checks that a request abandoned by the
+// client is neither a success nor a failure, while one the server was too
+// slow for counts as a timeout.
+func TestMakeRequestCanceledIsNeutral(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	tests := []struct {
+		name         string
+		ctx          func() (context.Context, context.CancelFunc)
+		wantCategory string
+		wantNeutral  bool
+	}{
+		{name: "canceled", ctx: func() (context.Context, context.CancelFunc) {
+			ctx, cancel := context.WithCancel(context.Background())
+			time.AfterFunc(50*time.Millisecond, cancel)
+			return ctx, cancel
+		}, wantCategory: categoryCanceled, wantNeutral: true},
+		{name: "deadline", ctx: func() (context.Context, context.CancelFunc) {
+			return context.WithTimeout(context.Background(), 50*time.Millisecond)
+		}, wantCategory: categoryTimeout},
+	}
+	stats := &RequestStats{}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			ctx, cancel := test.ctx()
+			defer cancel()
+			result := makeRequest(ctx, testConfig(t, "200"), server.Client(), testJob(server.URL), "token")
+			if result.Success || result.Category != test.wantCategory || result.Neutral != test.wantNeutral {
+				t.Errorf("success = %v, category = %q, neutral = %v; want false, %q, %v",
+					result.Success, result.Category, result.Neutral, test.wantCategory, test.wantNeutral)
+			}
+			stats.record(result)
+		})
+	}
+
+	summary := stats.summary(len(tests), time.Second)
+	if summary.SuccessCount != 0 || summary.FailureCount != 1 || summary.FailureCategories[categoryTimeout] != 1 || summary.NeutralCategories[categoryCanceled] != 1 {
+		t.Errorf("summary has %d successes, %d failures %v, neutral %v; want one timeout failure and one neutral cancel",
+			summary.SuccessCount, summary.FailureCount, summary.FailureCategories, summary.NeutralCategories)
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import "time"
+
+type memoryStats struct {
+	samples []uint64
+	phases  []string // phase of each sample
+	errors  int
+}
+
+// startMemoryMonitor samples container memory every interval until the
+// returned function is called, which hands back every sample taken along
+// with the phase the run was in at the time.
+func startMemoryMonitor(containerID string, interval time.Duration, phase *runPhase) func() memoryStats {
+	done := make(chan struct{})
+	result := make(chan memoryStats, 1)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		var stats memoryStats
+		for {
+			select {
+			case <-done:
+				result <- stats
+				return
+			case <-ticker.C:
+			}
+
+			usage, err := getContainerMemoryUsage(containerID)
+			if err != nil {
+				stats.errors++
+				continue
+			}
+			stats.samples = append(stats.samples, usage)
+			stats.phases = append(stats.phases, phase.get())
+		}
+	}()
+
+	return func() memoryStats {
+		close(done)
+		return <-result
+	}
+}
+
+// memoryDelta is signed because memory often ends below where it started;
+// subtracting the raw uint64 values would wrap around to a huge number.
+func memoryDelta(from, to uint64) int64 {
+	return int64(to) - int64(from)
+}
+
+// memoryVerdict decides -assert-memory-stable: settled memory may exceed the
+// initial usage by at most tolerance (a fraction of the initial usage).
+func memoryVerdict(initial, settled uint64, tolerance float64) string {
+	if float64(memoryDelta(initial, settled)) > float64(initial)*tolerance {
+		return memoryLeaked
+	}
+	return memoryStable
+}
+
+const (
+	memoryStable     = "stable"
+	memoryLeaked     = "leaked"
+	memoryUnverified = "unverified"
+)
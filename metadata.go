@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"runtime/debug"
+	"time"
+)
+
+// RunMetadata is the provenance stamped on every output so archived runs
+// can be grouped by configuration and traced back to where they ran.
+type RunMetadata struct {
+	ConfigHash  string    `json:"config_hash"`
+	StartedAt   time.Time `json:"started_at"`
+	Hostname    string    `json:"hostname"`
+	ToolVersion string    `json:"tool_version"`
+
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+func newRunMetadata(configHash string, labels map[string]string) RunMetadata {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+	return RunMetadata{
+		ConfigHash:  configHash,
+		StartedAt:   time.Now().UTC(),
+		Hostname:    hostname,
+		ToolVersion: toolVersion(),
+		Labels:      labels,
+	}
+}
+
+// toolVersion is the module version, or the VCS revision for the usual
+// (devel) build from a checkout.
+func toolVersion() string {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return "unknown"
+	}
+	version := info.Main.Version
+	for _, setting := range info.Settings {
+		if setting.Key == "vcs.revision" && len(setting.Value) >= 12 {
+			version += "+" + setting.Value[:12]
+		}
+	}
+	if version == "" {
+		return "unknown"
+	}
+	return version
+}
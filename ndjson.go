@@ -0,0 +1,36 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+)
+
+const ndjsonContentType = "application/x-ndjson"
+
+// buildNDJSONBody encodes one JSON object per image, with the image bytes in
+// base64 and the -form fields copied onto every line.
+func buildNDJSONBody(images []ImageFile, fields []formField) (*bytes.Buffer, error) {
+	body := &bytes.Buffer{}
+	encoder := json.NewEncoder(body)
+	for _, image := range images {
+		contentType := image.contentType
+		if contentType == "" {
+			contentType = "application/octet-stream"
+		}
+
+		line := make(map[string]string, len(fields)+3)
+		for _, field := range fields {
+			line[field.key] = field.value
+		}
+		line["filename"] = image.name
+		line["content_type"] = contentType
+		line["data"] = base64.StdEncoding.EncodeToString(image.data)
+
+		if err := encoder.Encode(line); err != nil {
+			return nil, fmt.Errorf("error encoding %s: %v", image.name, err)
+		}
+	}
+	return body, nil
+}
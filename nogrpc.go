@@ -0,0 +1,24 @@
+//go:build !grpc
+
+package main
+
+import (
+	"context"
+	"fmt"
+)
+
+// -mode grpc is only available in builds with -tags grpc, which keeps the
+// gRPC dependencies out of the default binary.
+const grpcSupported = false
+
+type grpcUploader struct{}
+
+func newGRPCUploader(cfg *Config, bearerToken string) (*grpcUploader, error) {
+	return nil, fmt.Errorf("built without gRPC support")
+}
+
+func (uploader *grpcUploader) makeRequest(ctx context.Context, job uploadJob) RequestResult {
+	return RequestResult{}
+}
+
+func (uploader *grpcUploader) close() {}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/clientcredentials"
+)
+
+// countingTokenSource counts the distinct access tokens handed out, which
+// is one per fetch since the wrapped source reuses a token until it is
+// about to expire.
+type countingTokenSource struct {
+	base oauth2.TokenSource
+
+	mutex   sync.Mutex
+	current string
+	fetches int
+}
+
+func (source *countingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := source.base.Token()
+	if err != nil {
+		return nil, err
+	}
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	if token.AccessToken != source.current {
+		source.current = token.AccessToken
+		source.fetches++
+	}
+	return token, nil
+}
+
+func (source *countingTokenSource) fetchCount() int {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	return source.fetches
+}
+
+// newOAuthTokenSource fetches a client-credentials token before the run and
+// installs it on client: a transport that replaces the static bearer token
+// with the current access token on every request. The token is fetched
+// again when it is about to expire, by whichever request comes first.
+func newOAuthTokenSource(cfg *Config, client *http.Client) (*countingTokenSource, error) {
+	credentials := &clientcredentials.Config{
+		ClientID:     cfg.ClientID,
+		ClientSecret: cfg.ClientSecret,
+		TokenURL:     cfg.OAuthTokenURL,
+		Scopes:       strings.FieldsFunc(cfg.Scope, func(r rune) bool { return r == ',' || r == ' ' }),
+	}
+	// Token requests go through the same transport, so -resolve and the
+	// TLS settings apply to them too.
+	ctx := context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: client.Transport, Timeout: requestTimeout})
+	source := &countingTokenSource{base: credentials.TokenSource(ctx)}
+
+	token, err := source.Token()
+	if err != nil {
+		return nil, fmt.Errorf("error fetching OAuth2 token from %s: %v", cfg.OAuthTokenURL, err)
+	}
+	if token.Expiry.IsZero() {
+		fmt.Printf("Fetched OAuth2 token from %s (no expiry)\n", cfg.OAuthTokenURL)
+	} else {
+		fmt.Printf("Fetched OAuth2 token from %s, expires in %v\n", cfg.OAuthTokenURL, time.Until(token.Expiry).Round(time.Second))
+	}
+
+	client.Transport = &oauth2.Transport{Source: source, Base: client.Transport}
+	return source, nil
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var latencyBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type latencyHistogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+func newLatencyHistogram() *latencyHistogram {
+	return &latencyHistogram{counts: make([]uint64, len(latencyBuckets))}
+}
+
+func (histogram *latencyHistogram) observe(seconds float64) {
+	for i, bound := range latencyBuckets {
+		if seconds <= bound {
+			histogram.counts[i]++
+		}
+	}
+	histogram.sum += seconds
+	histogram.count++
+}
+
+type openMetricsReporter struct {
+	path      string
+	histogram *latencyHistogram
+	mutex     sync.Mutex
+}
+
+func newOpenMetricsReporter(path string) *openMetricsReporter {
+	return &openMetricsReporter{path: path, histogram: newLatencyHistogram()}
+}
+
+func (reporter *openMetricsReporter) RecordRequest(result RequestResult) {
+	if result.StatusCode == 0 {
+		return
+	}
+
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.histogram.observe(result.Duration.Seconds())
+}
+
+func (reporter *openMetricsReporter) Finish(summary Summary) error {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	var out bytes.Buffer
+	writeOpenMetrics(&out, summary, reporter.histogram)
+
+	if err := os.WriteFile(reporter.path, out.Bytes(), 0644); err != nil {
+		return fmt.Errorf("error writing OpenMetrics file: %v", err)
+	}
+	return nil
+}
+
+func writeOpenMetrics(out *bytes.Buffer, summary Summary, histogram *latencyHistogram) {
+	// Every series carries the -label pairs after its own labels.
+	var runLabels []string
+	for _, key := range labelKeys(summary.Labels) {
+		runLabels = append(runLabels, key+`="`+labelEscaper.Replace(summary.Labels[key])+`"`)
+	}
+	series := func(name string, labels string) string {
+		all := runLabels
+		if labels != "" {
+			all = append([]string{labels}, runLabels...)
+		}
+		if len(all) == 0 {
+			return name
+		}
+		return name + "{" + strings.Join(all, ",") + "}"
+	}
+
+	fmt.Fprintf(out, "# TYPE uploader_run info\n")
+	fmt.Fprintf(out, "# HELP uploader_run Provenance of the run.\n")
+	fmt.Fprintf(out, "%s 1\n", series("uploader_run_info",
+		fmt.Sprintf("config_hash=\"%s\",hostname=\"%s\",tool_version=\"%s\"",
+			labelEscaper.Replace(summary.ConfigHash), labelEscaper.Replace(summary.Hostname), labelEscaper.Replace(summary.ToolVersion))))
+
+	fmt.Fprintf(out, "# TYPE uploader_requests counter\n")
+	fmt.Fprintf(out, "# HELP uploader_requests Upload requests by result.\n")
+	fmt.Fprintf(out, "%s %d\n", series("uploader_requests_total", `result="success"`), summary.SuccessCount)
+	fmt.Fprintf(out, "%s %d\n", series("uploader_requests_total", `result="failure"`), summary.FailureCount)
+
+	fmt.Fprintf(out, "# TYPE uploader_failures counter\n")
+	fmt.Fprintf(out, "# HELP uploader_failures Failed upload requests by category.\n")
+	categories := make([]string, 0, len(summary.FailureCategories))
+	for category := range summary.FailureCategories {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		fmt.Fprintf(out, "%s %d\n", series("uploader_failures_total", `category="`+labelEscaper.Replace(category)+`"`), summary.FailureCategories[category])
+	}
+
+	fmt.Fprintf(out, "# TYPE uploader_request_duration_seconds histogram\n")
+	fmt.Fprintf(out, "# HELP uploader_request_duration_seconds Latency of requests that received a response.\n")
+	for i, bound := range latencyBuckets {
+		fmt.Fprintf(out, "%s %d\n", series("uploader_request_duration_seconds_bucket", `le="`+strconv.FormatFloat(bound, 'g', -1, 64)+`"`), histogram.counts[i])
+	}
+	fmt.Fprintf(out, "%s %d\n", series("uploader_request_duration_seconds_bucket", `le="+Inf"`), histogram.count)
+	fmt.Fprintf(out, "%s %g\n", series("uploader_request_duration_seconds_sum", ""), histogram.sum)
+	fmt.Fprintf(out, "%s %d\n", series("uploader_request_duration_seconds_count", ""), histogram.count)
+
+	fmt.Fprintf(out, "# TYPE uploader_sent_bytes counter\n")
+	fmt.Fprintf(out, "# UNIT uploader_sent_bytes bytes\n")
+	fmt.Fprintf(out, "%s %d\n", series("uploader_sent_bytes_total", ""), summary.BytesSent)
+
+	fmt.Fprintf(out, "# TYPE uploader_run_duration_seconds gauge\n")
+	fmt.Fprintf(out, "# UNIT uploader_run_duration_seconds seconds\n")
+	fmt.Fprintf(out, "%s %g\n", series("uploader_run_duration_seconds", ""), summary.TotalDuration.Seconds())
+
+	if summary.MonitoringAvailable {
+		fmt.Fprintf(out, "# TYPE uploader_container_memory_bytes gauge\n")
+		fmt.Fprintf(out, "# UNIT uploader_container_memory_bytes bytes\n")
+		fmt.Fprintf(out, "%s %d\n", series("uploader_container_memory_bytes", `stage="initial"`), summary.InitialMemory)
+		fmt.Fprintf(out, "%s %d\n", series("uploader_container_memory_bytes", `stage="final"`), summary.FinalMemory)
+		if summary.SettledMemory > 0 {
+			fmt.Fprintf(out, "%s %d\n", series("uploader_container_memory_bytes", `stage="settled"`), summary.SettledMemory)
+		}
+	}
+
+	fmt.Fprintf(out, "# EOF\n")
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// partialSummary summarises the requests recorded so far, for runs that end
+// before the normal summary: a stuck -max-runtime stop or a panic in main.
+func partialSummary(stats *RequestStats, metadata RunMetadata, startTime time.Time) Summary {
+	summary := stats.summary(stats.recordedCount(), time.Since(startTime))
+	summary.RunMetadata = metadata
+	return summary
+}
+
+// finishOnPanic is deferred in main: a panic there would otherwise lose the
+// CSV rows still buffered and the JSON summary that is only written at the
+// end. It hands the partial results to the reporters, unless they already
+// finished, and re-panics so the crash is still reported as one. Panics in
+// other goroutines can't be recovered here; requests recover their own.
+func finishOnPanic(reporter Reporter, stats *RequestStats, metadata RunMetadata, startTime time.Time, finished *bool) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	if !*finished {
+		*finished = true
+		fmt.Printf("Panic: %v, writing partial results\n", recovered)
+		summary := partialSummary(stats, metadata, startTime)
+		summary.Panic = fmt.Sprint(recovered)
+		if err := reporter.Finish(summary); err != nil {
+			fmt.Printf("Error writing reports: %v\n", err)
+		}
+	}
+	panic(recovered)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// summaryRecorder keeps the summary it was finished with.
+type summaryRecorder struct {
+	finishes int
+	summary  Summary
+}
+
+func (recorder *summaryRecorder) RecordRequest(result RequestResult) {}
+
+func (recorder *summaryRecorder) Finish(summary Summary) error {
+	recorder.finishes++
+	recorder.summary = summary
+	return nil
+}
+
+func TestFinishOnPanic(t *testing.T) {
+	tests := []struct {
+		name         string
+		panicValue   any
+		finished     bool
+		wantFinishes int
+	}{
+		{name: "panic flushes the reports", panicValue: "boom", wantFinishes: 1},
+		{name: "reports already finished", panicValue: "boom", finished: true},
+		{name: "no panic", panicValue: nil},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "requests.csv")
+			csvReporter, err := newCSVReporter(path, RunMetadata{ConfigHash: "abc"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { csvReporter.file.Close() })
+			recorder := &summaryRecorder{}
+			reporter := multiReporter{csvReporter, recorder}
+
+			stats := &RequestStats{}
+			for i := range 3 {
+				result := RequestResult{RequestNum: i, ImageName: "face.jpg", StatusCode: 200, Success: true, Duration: time.Millisecond}
+				stats.record(result)
+				reporter.RecordRequest(result)
+			}
+
+			finished := test.finished
+			recovered := func() (recovered any) {
+				defer func() { recovered = recover() }()
+				defer finishOnPanic(reporter, stats, RunMetadata{}, time.Now(), &finished)
+				if test.panicValue != nil {
+					panic(test.panicValue)
+				}
+				return nil
+			}()
+
+			if recovered != test.panicValue {
+				t.Errorf("re-raised %v, want %v", recovered, test.panicValue)
+			}
+			if recorder.finishes != test.wantFinishes {
+				t.Fatalf("reporters finished %d times, want %d", recorder.finishes, test.wantFinishes)
+			}
+			if test.wantFinishes == 0 {
+				return
+			}
+			if !finished || recorder.summary.Panic != "boom" || recorder.summary.SuccessCount != 3 {
+				t.Errorf("finished = %v, summary panic %q with %d successes; want true, boom, 3",
+					finished, recorder.summary.Panic, recorder.summary.SuccessCount)
+			}
+			file, err := os.Open(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer file.Close()
+			rows, err := csv.NewReader(file).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(rows) != 4 {
+				t.Errorf("CSV has %d rows, want a header and 3 requests", len(rows))
+			}
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+)
+
+func partitionImages(images []ImageFile, workers int) [][]ImageFile {
+	if len(images) < workers {
+		fmt.Printf("Warning: only %d images for %d workers, some workers will share an image\n", len(images), workers)
+		partitions := make([][]ImageFile, workers)
+		for i := range partitions {
+			partitions[i] = []ImageFile{images[i%len(images)]}
+		}
+		return partitions
+	}
+
+	partitions := make([][]ImageFile, workers)
+	for i, image := range images {
+		partitions[i%workers] = append(partitions[i%workers], image)
+	}
+	return partitions
+}
+
+// imagePartitions cycles each worker through its own partition in the order
+// that worker sends, whichever request numbers the pool happens to give it.
+// Workers added after the split (SIGUSR1, -concurrency-sweep) reuse an
+// existing partition, which is reported once.
+type imagePartitions struct {
+	partitions [][]ImageFile
+
+	mutex  sync.Mutex
+	sent   map[int]int
+	warned bool
+}
+
+func newImagePartitions(images []ImageFile, workers int) *imagePartitions {
+	return &imagePartitions{partitions: partitionImages(images, workers), sent: make(map[int]int)}
+}
+
+func (partitions *imagePartitions) next(workerID int) ImageFile {
+	partitions.mutex.Lock()
+	defer partitions.mutex.Unlock()
+
+	owner := workerID % len(partitions.partitions)
+	if owner != workerID && !partitions.warned {
+		partitions.warned = true
+		fmt.Printf("Warning: worker %d was added after the images were partitioned and shares worker %d's images\n", workerID, owner)
+	}
+	partition := partitions.partitions[owner]
+	image := partition[partitions.sent[workerID]%len(partition)]
+	partitions.sent[workerID]++
+	return image
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"testing"
+)
+
+func TestImagePartitionsCyclePerWorker(t *testing.T) {
+	var images []ImageFile
+	for i := range 4 {
+		images = append(images, ImageFile{name: fmt.Sprintf("%d.jpg", i)})
+	}
+
+	tests := []struct {
+		name       string
+		workers    int
+		sends      []int
+		want       []string
+		wantWarned bool
+	}{
+		// With two workers taking turns, worker 0 would only ever see even
+		// request numbers; it must still reach both of its images.
+		{name: "own partition", workers: 2, sends: []int{0, 0, 0, 0}, want: []string{"0.jpg", "2.jpg", "0.jpg", "2.jpg"}},
+		{name: "interleaved", workers: 2, sends: []int{0, 1, 0, 1}, want: []string{"0.jpg", "1.jpg", "2.jpg", "3.jpg"}},
+		{name: "added worker shares", workers: 2, sends: []int{0, 2, 2}, want: []string{"0.jpg", "0.jpg", "2.jpg"}, wantWarned: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			partitions := newImagePartitions(images, test.workers)
+			var got []string
+			for _, workerID := range test.sends {
+				got = append(got, partitions.next(workerID).name)
+			}
+			if !slices.Equal(got, test.want) {
+				t.Errorf("sent %v, want %v", got, test.want)
+			}
+			if partitions.warned != test.wantWarned {
+				t.Errorf("warned = %v, want %v", partitions.warned, test.wantWarned)
+			}
+		})
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import "fmt"
+
+func plannedPayloadBytes(images []ImageFile, totalRequests int) int64 {
+	var cycleBytes int64
+	for _, image := range images {
+		cycleBytes += int64(len(image.data))
+	}
+
+	fullCycles := totalRequests / len(images)
+	total := int64(fullCycles) * cycleBytes
+	for i := 0; i < totalRequests%len(images); i++ {
+		total += int64(len(images[i].data))
+	}
+	return total
+}
+
+func averageImageBytes(images []ImageFile) int64 {
+	var total int64
+	for _, image := range images {
+		total += int64(len(image.data))
+	}
+	return total / int64(len(images))
+}
+
+func formatBytes(bytes int64) string {
+	megabytes := float64(bytes) / 1024 / 1024
+	if bytes >= 1<<30 {
+		return fmt.Sprintf("%.2f GB (%.2f MB)", megabytes/1024, megabytes)
+	}
+	return fmt.Sprintf("%.2f MB", megabytes)
+}
@@ -0,0 +1,24 @@
+package main
+
+import (
+	"cmp"
+	"math"
+	"slices"
+)
+
+// percentiles returns the nearest-rank value for each p (0-100) of values,
+// which may be in any order.
+func percentiles[T cmp.Ordered](values []T, ps ...float64) []T {
+	result := make([]T, len(ps))
+	if len(values) == 0 {
+		return result
+	}
+
+	sorted := slices.Clone(values)
+	slices.Sort(sorted)
+	for i, p := range ps {
+		rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+		result[i] = sorted[min(max(rank, 1), len(sorted))-1]
+	}
+	return result
+}
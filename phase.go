@@ -0,0 +1,74 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+)
+
+// Phases of a run, in the order it goes through them. Memory samples are
+// tagged with the phase they were taken in, so growth while requests are
+// still being sent can be told apart from growth while they drain. A
+// -concurrency-sweep replaces steady with one phase per level.
+const (
+	phasePrewarm = "prewarm"
+	phaseSteady  = "steady"
+	phaseSweep   = "sweep"
+	phaseDrain   = "drain"
+)
+
+// sweepPhase names the phase of one -concurrency-sweep level.
+func sweepPhase(level int) string {
+	return fmt.Sprintf("%s-%d", phaseSweep, level)
+}
+
+type runPhase struct {
+	current atomic.Value
+}
+
+func newRunPhase(phase string) *runPhase {
+	tracker := &runPhase{}
+	tracker.set(phase)
+	return tracker
+}
+
+func (tracker *runPhase) set(phase string) {
+	tracker.current.Store(phase)
+}
+
+func (tracker *runPhase) get() string {
+	return tracker.current.Load().(string)
+}
+
+type PhaseMemory struct {
+	Phase   string `json:"phase"`
+	Samples int    `json:"samples"`
+	Min     uint64 `json:"min_bytes"`
+	Average uint64 `json:"average_bytes"`
+	Max     uint64 `json:"max_bytes"`
+}
+
+// phaseMemory summarises the samples of each phase in the order the phases
+// were first seen.
+func phaseMemory(stats memoryStats) []PhaseMemory {
+	var phases []PhaseMemory
+	index := make(map[string]int)
+	totals := make(map[string]uint64)
+	for i, sample := range stats.samples {
+		phase := stats.phases[i]
+		position, seen := index[phase]
+		if !seen {
+			position = len(phases)
+			index[phase] = position
+			phases = append(phases, PhaseMemory{Phase: phase, Min: sample})
+		}
+		entry := &phases[position]
+		entry.Samples++
+		entry.Min = min(entry.Min, sample)
+		entry.Max = max(entry.Max, sample)
+		totals[phase] += sample
+	}
+	for i := range phases {
+		phases[i].Average = totals[phases[i].Phase] / uint64(phases[i].Samples)
+	}
+	return phases
+}
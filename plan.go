@@ -0,0 +1,115 @@
+package main
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+type planEntry struct {
+	image      ImageFile
+	listID     string
+	formFields []formField
+}
+
+var faceListPattern = regexp.MustCompile(`/faceLists/[^/]+/`)
+
+func withFaceListID(url string, listID string) string {
+	return faceListPattern.ReplaceAllLiteralString(url, "/faceLists/"+listID+"/")
+}
+
+// loadPlan reads a CSV plan with a required "image" column, an optional "list_id"
+// column, and any other columns sent as form fields. Image paths are relative to the plan file.
+func loadPlan(path string) ([]planEntry, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("error opening plan: %v", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("error reading plan header: %v", err)
+	}
+
+	imageColumn, listColumn := -1, -1
+	for i, column := range header {
+		header[i] = strings.TrimSpace(column)
+		switch header[i] {
+		case "image":
+			imageColumn = i
+		case "list_id":
+			listColumn = i
+		case "":
+			return nil, fmt.Errorf("plan header: column %d has no name", i+1)
+		}
+	}
+	if imageColumn < 0 {
+		return nil, fmt.Errorf("plan header: missing required \"image\" column")
+	}
+
+	baseDir := filepath.Dir(path)
+	loaded := make(map[string]ImageFile)
+
+	var entries []planEntry
+	var rowErrors []error
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			rowErrors = append(rowErrors, err)
+			continue
+		}
+		line, _ := reader.FieldPos(0)
+
+		imagePath := record[imageColumn]
+		if imagePath == "" {
+			rowErrors = append(rowErrors, fmt.Errorf("line %d: empty image", line))
+			continue
+		}
+		if !filepath.IsAbs(imagePath) {
+			imagePath = filepath.Join(baseDir, imagePath)
+		}
+
+		image, ok := loaded[imagePath]
+		if !ok {
+			image, err = loadImageFromFile(imagePath)
+			if err != nil {
+				rowErrors = append(rowErrors, fmt.Errorf("line %d: %v", line, err))
+				continue
+			}
+			loaded[imagePath] = image
+		}
+
+		entry := planEntry{image: image}
+		for i, value := range record {
+			switch {
+			case i == imageColumn || value == "":
+			case i == listColumn:
+				entry.listID = value
+			default:
+				entry.formFields = append(entry.formFields, formField{key: header[i], value: value})
+			}
+		}
+		entries = append(entries, entry)
+	}
+
+	if len(rowErrors) > 0 {
+		return nil, fmt.Errorf("invalid plan rows:\n%v", errors.Join(rowErrors...))
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("plan has no rows")
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,106 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+type workerPool struct {
+	jobs     chan int
+	handle   func(workerID int, requestNum int)
+	mutex    sync.Mutex
+	size     int
+	retiring int
+	nextID   int
+	wg       sync.WaitGroup
+
+	busy      atomic.Int64
+	completed atomic.Int64
+}
+
+func newWorkerPool(size int, queueSize int, handle func(workerID int, requestNum int)) *workerPool {
+	pool := &workerPool{
+		jobs:   make(chan int, queueSize),
+		handle: handle,
+	}
+	pool.resize(size)
+	return pool
+}
+
+func (pool *workerPool) currentSize() int {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	return pool.size
+}
+
+// resize grows the pool immediately; shrinking retires workers as they finish their current request.
+func (pool *workerPool) resize(size int) int {
+	if size < 1 {
+		size = 1
+	}
+
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+
+	for pool.size < size {
+		if pool.retiring > 0 {
+			pool.retiring--
+		} else {
+			pool.wg.Add(1)
+			go pool.worker(pool.nextID)
+			pool.nextID++
+		}
+		pool.size++
+	}
+
+	if pool.size > size {
+		pool.retiring += pool.size - size
+		pool.size = size
+	}
+
+	return pool.size
+}
+
+func (pool *workerPool) shouldRetire() bool {
+	pool.mutex.Lock()
+	defer pool.mutex.Unlock()
+	if pool.retiring > 0 {
+		pool.retiring--
+		return true
+	}
+	return false
+}
+
+func (pool *workerPool) worker(workerID int) {
+	defer pool.wg.Done()
+	for requestNum := range pool.jobs {
+		pool.busy.Add(1)
+		pool.handle(workerID, requestNum)
+		pool.busy.Add(-1)
+		pool.completed.Add(1)
+		if pool.shouldRetire() {
+			return
+		}
+	}
+}
+
+func (pool *workerPool) inFlight() int {
+	return int(pool.busy.Load())
+}
+
+func (pool *workerPool) completedCount() int64 {
+	return pool.completed.Load()
+}
+
+func (pool *workerPool) queueLength() int {
+	return len(pool.jobs)
+}
+
+func (pool *workerPool) submit(requestNum int) {
+	pool.jobs <- requestNum
+}
+
+func (pool *workerPool) wait() {
+	close(pool.jobs)
+	pool.wg.Wait()
+}
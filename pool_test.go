@@ -0,0 +1,101 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestWorkerPoolResize(t *testing.T) {
+	tests := []struct {
+		name        string
+		initial     int
+		resizes     []int
+		wantSizes   []int
+		wantStarted int
+	}{
+		{name: "grow", initial: 2, resizes: []int{5}, wantSizes: []int{5}, wantStarted: 5},
+		{name: "shrink", initial: 4, resizes: []int{1}, wantSizes: []int{1}, wantStarted: 4},
+		{name: "at least one", initial: 3, resizes: []int{0, -2}, wantSizes: []int{1, 1}, wantStarted: 3},
+		{name: "grow cancels retirements", initial: 3, resizes: []int{5, 2, 4}, wantSizes: []int{5, 2, 4}, wantStarted: 5},
+		{name: "grow past retirements", initial: 3, resizes: []int{1, 6}, wantSizes: []int{1, 6}, wantStarted: 6},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			pool := newWorkerPool(test.initial, 0, func(workerID int, requestNum int) {})
+			for i, size := range test.resizes {
+				if got := pool.resize(size); got != test.wantSizes[i] {
+					t.Errorf("resize(%d) = %d, want %d", size, got, test.wantSizes[i])
+				}
+			}
+			if got := pool.currentSize(); got != test.wantSizes[len(test.wantSizes)-1] {
+				t.Errorf("currentSize() = %d, want %d", got, test.wantSizes[len(test.wantSizes)-1])
+			}
+			if pool.nextID != test.wantStarted {
+				t.Errorf("started %d workers, want %d", pool.nextID, test.wantStarted)
+			}
+			pool.wait()
+		})
+	}
+}
+
+// TestWorkerPoolShrinkLimitsConcurrency checks that after shrinking, the
+// retired workers stop taking requests once their current one is done.
+func TestWorkerPoolShrinkLimitsConcurrency(t *testing.T) {
+	var running, peak atomic.Int64
+	var shrunk atomic.Bool
+	var workers sync.Map
+	release := make(chan struct{})
+	pool := newWorkerPool(4, 0, func(workerID int, requestNum int) {
+		peak.Store(max(peak.Load(), running.Add(1)))
+		defer running.Add(-1)
+		if !shrunk.Load() {
+			<-release
+			return
+		}
+		workers.Store(workerID, true)
+		time.Sleep(time.Millisecond)
+	})
+
+	go func() {
+		for i := range 4 {
+			pool.submit(i)
+		}
+	}()
+	waitFor(t, func() bool { return pool.inFlight() == 4 })
+	pool.resize(1)
+	close(release)
+	waitFor(t, func() bool {
+		pool.mutex.Lock()
+		defer pool.mutex.Unlock()
+		return pool.completedCount() == 4 && pool.retiring == 0
+	})
+
+	shrunk.Store(true)
+	peak.Store(0)
+	for i := range 10 {
+		pool.submit(4 + i)
+	}
+	pool.wait()
+
+	if got := peak.Load(); got != 1 {
+		t.Errorf("%d requests ran at once after shrinking to 1", got)
+	}
+	count := 0
+	workers.Range(func(key, value any) bool { count++; return true })
+	if count != 1 || pool.completedCount() != 14 {
+		t.Errorf("%d workers handled %d requests, want 1 worker and 14", count, pool.completedCount())
+	}
+}
+
+func waitFor(t *testing.T, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for the pool")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
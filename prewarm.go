@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+)
+
+// prewarmConnections opens up to count connections to targetURL before the
+// measured run, so its first requests don't pay for TCP and TLS setup. All
+// HEAD requests are released at once: each one finding no idle connection
+// dials its own, and a dial that loses the race to a freed connection still
+// ends up in the idle pool. The status doesn't matter, only the connection.
+// It returns how many connections were opened.
+func prewarmConnections(client *http.Client, targetURL string, userAgent string, count int) int {
+	var opened atomic.Int64
+	trace := &httptrace.ClientTrace{
+		ConnectDone: func(_, _ string, err error) {
+			if err == nil {
+				opened.Add(1)
+			}
+		},
+	}
+	ctx := httptrace.WithClientTrace(context.Background(), trace)
+
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for range count {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req, err := http.NewRequestWithContext(ctx, http.MethodHead, targetURL, nil)
+			if err != nil {
+				return
+			}
+			req.Header.Set("User-Agent", userAgent)
+			<-start
+			resp, err := client.Do(req)
+			if err != nil {
+				return
+			}
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}()
+	}
+	close(start)
+	wg.Wait()
+	return int(opened.Load())
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+)
+
+const (
+	// probeBaseSize is the synthetic image every probe starts from; larger
+	// probes pad it, since generating big noise images is slow and size is
+	// what the server's limit is about.
+	probeBaseSize = 16 << 10
+	// probeResolution stops the search once the bounds are this close.
+	probeResolution = 1 << 10
+	probeMaxSteps   = 40
+)
+
+type probeOutcome struct {
+	size   int64
+	result RequestResult
+}
+
+// probeMaxSize binary-searches the payload size between a small synthetic
+// image and limit for the largest upload the server still accepts.
+func probeMaxSize(ctx context.Context, cfg *Config, client *http.Client, bearerToken string, limit int64) error {
+	base, err := generateSyntheticImage(probeBaseSize, cfg.SyntheticFormat, 0, 0)
+	if err != nil {
+		return err
+	}
+
+	step := 0
+	probe := func(size int64) probeOutcome {
+		image := base
+		image.data = append(make([]byte, 0, size), base.data...)
+		image.data = image.data[:max(size, int64(len(base.data)))]
+		result := makeRequest(ctx, cfg, client, uploadJob{requestNum: step, url: cfg.URL, image: image}, bearerToken)
+		step++
+		verdict := "rejected"
+		if result.Success {
+			verdict = "accepted"
+		}
+		fmt.Printf("Probe %d: %s %s (%s)\n", step, formatBytes(size), verdict, describeOutcome(result))
+		return probeOutcome{size: size, result: result}
+	}
+
+	accepted := probe(int64(len(base.data)))
+	if !accepted.result.Success {
+		return fmt.Errorf("the smallest probe of %s was already rejected (%s)", formatBytes(accepted.size), describeOutcome(accepted.result))
+	}
+	rejected := probe(limit)
+	if rejected.result.Success {
+		fmt.Printf("\nThe server accepted the -probe-limit of %s; its limit, if any, is higher\n", formatBytes(limit))
+		return nil
+	}
+
+	for rejected.size-accepted.size > probeResolution && step < probeMaxSteps && ctx.Err() == nil {
+		outcome := probe(accepted.size + (rejected.size-accepted.size)/2)
+		if outcome.result.Success {
+			accepted = outcome
+		} else {
+			rejected = outcome
+		}
+	}
+
+	fmt.Printf("\n=== Максимальный размер загрузки ===\n")
+	fmt.Printf("Наибольший принятый размер: %s (%d байт)\n", formatBytes(accepted.size), accepted.size)
+	fmt.Printf("Наименьший отклонённый размер: %s (%d байт), ответ: %s\n", formatBytes(rejected.size), rejected.size, describeOutcome(rejected.result))
+	if rejected.result.StatusCode != http.StatusRequestEntityTooLarge {
+		fmt.Printf("Внимание: на границе сервер ответил не 413, возможно отказ вызван не размером\n")
+	}
+	return nil
+}
+
+func describeOutcome(result RequestResult) string {
+	if result.StatusCode > 0 {
+		return fmt.Sprintf("status %d", result.StatusCode)
+	}
+	if result.Err != nil {
+		return fmt.Sprintf("%s: %v", result.Category, result.Err)
+	}
+	return result.Category
+}
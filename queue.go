@@ -0,0 +1,49 @@
+package main
+
+import "time"
+
+const queueSampleInterval = 100 * time.Millisecond
+
+type queueStats struct {
+	capacity     int
+	average      float64
+	max          int
+	fullFraction float64
+}
+
+func startQueueSampler(pool *workerPool, capacity int) func() queueStats {
+	done := make(chan struct{})
+	result := make(chan queueStats, 1)
+	go func() {
+		ticker := time.NewTicker(queueSampleInterval)
+		defer ticker.Stop()
+
+		stats := queueStats{capacity: capacity}
+		samples, total, full := 0, 0, 0
+		for {
+			select {
+			case <-done:
+				if samples > 0 {
+					stats.average = float64(total) / float64(samples)
+					stats.fullFraction = float64(full) / float64(samples)
+				}
+				result <- stats
+				return
+			case <-ticker.C:
+			}
+
+			length := pool.queueLength()
+			samples++
+			total += length
+			stats.max = max(stats.max, length)
+			if length >= capacity {
+				full++
+			}
+		}
+	}()
+
+	return func() queueStats {
+		close(done)
+		return <-result
+	}
+}
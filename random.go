@@ -0,0 +1,27 @@
+package main
+
+import (
+	"math/rand/v2"
+	"sync"
+)
+
+type lockedRand struct {
+	mutex sync.Mutex
+	rng   *rand.Rand
+}
+
+func newLockedRand(seed uint64) *lockedRand {
+	return &lockedRand{rng: rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))}
+}
+
+func (random *lockedRand) Float64() float64 {
+	random.mutex.Lock()
+	defer random.mutex.Unlock()
+	return random.rng.Float64()
+}
+
+func (random *lockedRand) IntN(n int) int {
+	random.mutex.Lock()
+	defer random.mutex.Unlock()
+	return random.rng.IntN(n)
+}
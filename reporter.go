@@ -0,0 +1,726 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type RequestResult struct {
+	RequestNum     int
+	ImageName      string
+	TraceID        string
+	StatusCode     int
+	Duration       time.Duration
+	BytesSent      int64
+	Images         int
+	PayloadBytes   int64
+	ConnWait       time.Duration
+	UploadTime     time.Duration
+	ServerTime     time.Duration
+	DownloadTime   time.Duration
+	ForcedClose    bool
+	Breakdown      requestBreakdown
+	Chunks         int
+	ChunkTime      time.Duration
+	Success        bool
+	Neutral        bool
+	Verified       bool
+	Category       string
+	Err            error
+	Corruption     string
+	RemoteAddr     string
+	ResponseBody   string
+	ErrorMessage   string
+	Protocol       string
+	NewConnections int
+	Attempts       int
+}
+
+type Summary struct {
+	RunMetadata
+	TotalRequests              int                    `json:"total_requests"`
+	ResumedFrom                int                    `json:"resumed_from,omitempty"`
+	FolderShares               []FolderShare          `json:"folder_shares,omitempty"`
+	MaxRuntimeReached          bool                   `json:"max_runtime_reached,omitempty"`
+	InProgress                 bool                   `json:"in_progress,omitempty"`
+	Interrupted                bool                   `json:"interrupted,omitempty"`
+	Panic                      string                 `json:"panic,omitempty"`
+	SuccessCount               int                    `json:"success_count"`
+	FailureCount               int                    `json:"failure_count"`
+	FailureCategories          map[string]int         `json:"failure_categories,omitempty"`
+	ErrorMessages              []ErrorMessage         `json:"error_messages,omitempty"`
+	NeutralCategories          map[string]int         `json:"neutral_categories,omitempty"`
+	ConnectionFailuresByRemote map[string]int         `json:"connection_failures_by_remote,omitempty"`
+	ConnectionProtocols        map[string]int         `json:"connection_protocols,omitempty"`
+	ResolvedDials              map[string]int         `json:"resolved_dials,omitempty"`
+	SourceDials                int                    `json:"source_dials,omitempty"`
+	SourceBindFailures         int                    `json:"source_bind_failures,omitempty"`
+	PrewarmedConnections       int                    `json:"prewarmed_connections,omitempty"`
+	SessionLogins              int                    `json:"session_logins,omitempty"`
+	OAuthTokens                int                    `json:"oauth_tokens,omitempty"`
+	Retries                    int                    `json:"retries,omitempty"`
+	RetriesDenied              int                    `json:"retries_denied,omitempty"`
+	SuccessAttempts            map[int]int            `json:"success_attempts,omitempty"`
+	FailureAttempts            map[int]int            `json:"failure_attempts,omitempty"`
+	TotalDuration              time.Duration          `json:"total_duration_ns"`
+	AverageLatency             time.Duration          `json:"average_latency_ns"`
+	LatencyP50                 time.Duration          `json:"latency_p50_ns,omitempty"`
+	LatencyP90                 time.Duration          `json:"latency_p90_ns,omitempty"`
+	LatencyP99                 time.Duration          `json:"latency_p99_ns,omitempty"`
+	SLOs                       []SLOCompliance        `json:"slos,omitempty"`
+	StatusLatencies            []StatusLatency        `json:"status_latencies,omitempty"`
+	RequestsPerSecond          float64                `json:"requests_per_second"`
+	NewConnections             int                    `json:"new_connections"`
+	ConnectionsPerSecond       float64                `json:"connections_per_second"`
+	RequestsPerConnection      float64                `json:"requests_per_connection,omitempty"`
+	ClosedRequests             int                    `json:"closed_requests,omitempty"`
+	ClosedAverageLatency       time.Duration          `json:"closed_average_latency_ns,omitempty"`
+	KeepAliveAverageLatency    time.Duration          `json:"keep_alive_average_latency_ns,omitempty"`
+	ArrivalMode                string                 `json:"arrival_mode,omitempty"`
+	ArrivalTargetMean          time.Duration          `json:"arrival_target_mean_ns,omitempty"`
+	ArrivalMean                time.Duration          `json:"arrival_mean_ns,omitempty"`
+	ArrivalP50                 time.Duration          `json:"arrival_p50_ns,omitempty"`
+	ArrivalP90                 time.Duration          `json:"arrival_p90_ns,omitempty"`
+	ArrivalP99                 time.Duration          `json:"arrival_p99_ns,omitempty"`
+	BurstSize                  int                    `json:"burst_size,omitempty"`
+	BurstInterval              time.Duration          `json:"burst_interval_ns,omitempty"`
+	Bursts                     []BurstStats           `json:"bursts,omitempty"`
+	SlowestRequests            []SlowRequest          `json:"slowest_requests,omitempty"`
+	ProblemImages              []ImageFailureRate     `json:"problem_images,omitempty"`
+	BytesSent                  int64                  `json:"bytes_sent"`
+	Format                     string                 `json:"format"`
+	TransferEncoding           string                 `json:"transfer_encoding,omitempty"`
+	SyntheticImage             string                 `json:"synthetic_image,omitempty"`
+	DuplicateImages            int                    `json:"duplicate_images,omitempty"`
+	DedupeSavedBytes           int64                  `json:"dedupe_saved_bytes,omitempty"`
+	BatchSize                  int                    `json:"batch_size"`
+	ImagesSent                 int                    `json:"images_sent"`
+	DistinctImages             int                    `json:"distinct_images"`
+	PayloadBytes               int64                  `json:"payload_bytes"`
+	ConnWaitSamples            int                    `json:"conn_wait_samples,omitempty"`
+	AverageConnWait            time.Duration          `json:"average_conn_wait_ns,omitempty"`
+	MaxConnWait                time.Duration          `json:"max_conn_wait_ns,omitempty"`
+	RequestPhases              []RequestPhase         `json:"request_phases,omitempty"`
+	PhaseBreakdown             []PhaseShare           `json:"phase_breakdown,omitempty"`
+	WorkerImages               map[int]map[string]int `json:"worker_images,omitempty"`
+	SizeDistribution           []SizeBucket           `json:"size_distribution,omitempty"`
+	ChunkCount                 int                    `json:"chunk_count,omitempty"`
+	AverageChunkTime           time.Duration          `json:"average_chunk_time_ns,omitempty"`
+	Sweep                      []SweepLevel           `json:"sweep,omitempty"`
+	SweepPlateau               int                    `json:"sweep_plateau,omitempty"`
+	SweepP99Knee               int                    `json:"sweep_p99_knee,omitempty"`
+	ConfiguredConcurrency      int                    `json:"configured_concurrency,omitempty"`
+	AverageConcurrency         float64                `json:"average_concurrency"`
+	MaxConcurrency             int                    `json:"max_concurrency"`
+	HostConcurrency            []HostConcurrency      `json:"host_concurrency,omitempty"`
+	QueueCapacity              int                    `json:"queue_capacity,omitempty"`
+	QueueAverage               float64                `json:"queue_average,omitempty"`
+	QueueMax                   int                    `json:"queue_max,omitempty"`
+	QueueFullFraction          float64                `json:"queue_full_fraction,omitempty"`
+	WSConnections              int                    `json:"ws_connections,omitempty"`
+	WSReuses                   int                    `json:"ws_reuses,omitempty"`
+	MalformedResponses         map[string]map[int]int `json:"malformed_responses,omitempty"`
+	VerifiedCount              int                    `json:"verified_count,omitempty"`
+	VerifyFailures             int                    `json:"verify_failures,omitempty"`
+	BreakerOpenCount           int                    `json:"breaker_open_count,omitempty"`
+	BreakerOpenTime            time.Duration          `json:"breaker_open_time_ns,omitempty"`
+	MonitoringAvailable        bool                   `json:"monitoring_available"`
+	InitialMemory              uint64                 `json:"initial_memory_bytes"`
+	FinalMemory                uint64                 `json:"final_memory_bytes"`
+	NetworkAvailable           bool                   `json:"network_available"`
+	NetworkRxBytes             uint64                 `json:"network_rx_bytes,omitempty"`
+	NetworkTxBytes             uint64                 `json:"network_tx_bytes,omitempty"`
+	MemorySamples              int                    `json:"memory_samples,omitempty"`
+	PeakMemory                 uint64                 `json:"peak_memory_bytes,omitempty"`
+	AverageMemory              uint64                 `json:"average_memory_bytes,omitempty"`
+	MemoryP50                  uint64                 `json:"memory_p50_bytes,omitempty"`
+	MemoryP90                  uint64                 `json:"memory_p90_bytes,omitempty"`
+	MemoryP99                  uint64                 `json:"memory_p99_bytes,omitempty"`
+	MemoryPhases               []PhaseMemory          `json:"memory_phases,omitempty"`
+	Cooldown                   time.Duration          `json:"cooldown_ns,omitempty"`
+	MemoryVerdict              string                 `json:"memory_verdict,omitempty"`
+	SettledMemory              uint64                 `json:"settled_memory_bytes,omitempty"`
+}
+
+const networkDiscrepancyThreshold = 0.25
+
+type Reporter interface {
+	RecordRequest(result RequestResult)
+	Finish(summary Summary) error
+}
+
+type multiReporter []Reporter
+
+// finishGuard drops requests recorded after Finish. A run that exits with
+// workers still in flight, such as a stuck -max-runtime stop, finishes the
+// reporters under them; a late result would otherwise be sent on the
+// closed events channel or written to a closed file.
+type finishGuard struct {
+	reporter Reporter
+
+	mutex    sync.RWMutex
+	finished bool
+}
+
+func (guard *finishGuard) RecordRequest(result RequestResult) {
+	guard.mutex.RLock()
+	defer guard.mutex.RUnlock()
+	if !guard.finished {
+		guard.reporter.RecordRequest(result)
+	}
+}
+
+func (guard *finishGuard) Finish(summary Summary) error {
+	guard.mutex.Lock()
+	guard.finished = true
+	guard.mutex.Unlock()
+	return guard.reporter.Finish(summary)
+}
+
+func (reporters multiReporter) RecordRequest(result RequestResult) {
+	for _, reporter := range reporters {
+		reporter.RecordRequest(result)
+	}
+}
+
+func (reporters multiReporter) Finish(summary Summary) error {
+	var firstErr error
+	for _, reporter := range reporters {
+		if err := reporter.Finish(summary); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func newReporter(cfg *Config, metadata RunMetadata) (Reporter, error) {
+	reporters := multiReporter{&consoleReporter{}}
+
+	if cfg.JSONOutput != "" {
+		if cfg.FormatCompat == "k6" {
+			reporter := &k6Reporter{path: cfg.JSONOutput}
+			if cfg.Forever {
+				reporter.sampleLimit = foreverSampleLimit
+			}
+			reporters = append(reporters, reporter)
+		} else {
+			reporters = append(reporters, &jsonReporter{path: cfg.JSONOutput})
+		}
+	}
+
+	if cfg.ReportFile != "" {
+		reporters = append(reporters, &snapshotReporter{path: cfg.ReportFile})
+	}
+
+	if cfg.CSVOutput != "" {
+		reporter, err := newCSVReporter(cfg.CSVOutput, metadata)
+		if err != nil {
+			return nil, err
+		}
+		reporters = append(reporters, reporter)
+	}
+
+	if cfg.SweepCSV != "" {
+		reporters = append(reporters, &sweepCSVReporter{path: cfg.SweepCSV})
+	}
+
+	if cfg.OpenMetricsOutput != "" {
+		reporters = append(reporters, newOpenMetricsReporter(cfg.OpenMetricsOutput))
+	}
+
+	if cfg.EventsOutput != "" {
+		reporter, err := newEventsReporter(cfg.EventsOutput)
+		if err != nil {
+			return nil, err
+		}
+		reporters = append(reporters, reporter)
+	}
+
+	if cfg.FailuresFile != "" {
+		reporter, err := newFailuresReporter(cfg.FailuresFile)
+		if err != nil {
+			return nil, err
+		}
+		reporters = append(reporters, reporter)
+	}
+
+	if cfg.SQLiteOutput != "" {
+		reporter, err := newSQLiteReporter(cfg, metadata)
+		if err != nil {
+			return nil, err
+		}
+		reporters = append(reporters, reporter)
+	}
+
+	return reporters, nil
+}
+
+type consoleReporter struct{}
+
+func (reporter *consoleReporter) RecordRequest(result RequestResult) {}
+
+func (reporter *consoleReporter) Finish(summary Summary) error {
+	fmt.Printf("\n=== Результаты тестирования ===\n")
+	fmt.Printf("Хэш конфигурации: %s (запуск %s на %s, версия %s)\n",
+		summary.ConfigHash, summary.StartedAt.Local().Format(time.DateTime), summary.Hostname, summary.ToolVersion)
+	if len(summary.Labels) > 0 {
+		var labels []string
+		for _, key := range labelKeys(summary.Labels) {
+			labels = append(labels, key+"="+summary.Labels[key])
+		}
+		fmt.Printf("Метки запуска: %s\n", strings.Join(labels, ", "))
+	}
+	fmt.Printf("Всего запросов: %d\n", summary.TotalRequests)
+	if summary.Panic != "" {
+		fmt.Printf("Запуск аварийно завершился (panic: %s), результаты неполные\n", summary.Panic)
+	}
+	if summary.MaxRuntimeReached {
+		fmt.Printf("Запуск остановлен по -max-runtime, результаты неполные\n")
+	}
+	if summary.Interrupted {
+		fmt.Printf("Запуск -forever остановлен сигналом\n")
+	}
+	if summary.ResumedFrom > 0 {
+		fmt.Printf("Продолжение прерванного запуска: пропущено %d уже выполненных запросов\n", summary.ResumedFrom)
+	}
+	if summary.SyntheticImage != "" {
+		fmt.Printf("Данные синтетические: %s\n", summary.SyntheticImage)
+	}
+	if summary.DuplicateImages > 0 {
+		fmt.Printf("Дубликатов изображений в памяти объединено: %d, сэкономлено %s\n", summary.DuplicateImages, formatBytes(summary.DedupeSavedBytes))
+	}
+	fmt.Printf("Успешных запросов: %d\n", summary.SuccessCount)
+	fmt.Printf("Неудачных запросов: %d\n", summary.FailureCount)
+	printCategories(summary.FailureCategories)
+	if len(summary.ConnectionFailuresByRemote) > 0 {
+		fmt.Printf("Ошибки соединения по адресу сервера:\n")
+		printCategories(summary.ConnectionFailuresByRemote)
+	}
+	if len(summary.ErrorMessages) > 0 {
+		fmt.Printf("Частые сообщения об ошибках сервера:\n")
+		for _, message := range summary.ErrorMessages {
+			fmt.Printf("  %s: %d\n", message.Message, message.Count)
+		}
+	}
+	if summary.Retries > 0 || summary.RetriesDenied > 0 {
+		fmt.Printf("Повторных попыток: %d, отклонено бюджетом повторов: %d\n", summary.Retries, summary.RetriesDenied)
+	}
+	if len(summary.SuccessAttempts) > 0 || len(summary.FailureAttempts) > 0 {
+		fmt.Printf("Успех с попытки: %s\n", formatAttempts(summary.SuccessAttempts))
+		fmt.Printf("Окончательная неудача после попыток: %s\n", formatAttempts(summary.FailureAttempts))
+	}
+	if summary.OAuthTokens > 1 {
+		fmt.Printf("Токен OAuth2 обновлялся: %d раз\n", summary.OAuthTokens-1)
+	}
+	if summary.SessionLogins > 1 {
+		fmt.Printf("Сессия обновлялась после 401: %d раз\n", summary.SessionLogins-1)
+	}
+	if len(summary.ResolvedDials) > 0 {
+		fmt.Printf("Соединения по закреплённым адресам (-resolve):\n")
+		printCategories(summary.ResolvedDials)
+	}
+	if summary.SourceDials > 0 || summary.SourceBindFailures > 0 {
+		fmt.Printf("Соединений через -source-ports/-reuseaddr: %d, ошибок привязки порта: %d\n", summary.SourceDials, summary.SourceBindFailures)
+	}
+	if summary.PrewarmedConnections > 0 {
+		fmt.Printf("Соединений открыто заранее (-prewarm): %d\n", summary.PrewarmedConnections)
+	}
+	if len(summary.ConnectionProtocols) > 0 {
+		fmt.Printf("Протоколы новых соединений:\n")
+		printCategories(summary.ConnectionProtocols)
+	}
+	if len(summary.NeutralCategories) > 0 {
+		fmt.Printf("Нейтральных ответов (не успех и не ошибка):\n")
+		printCategories(summary.NeutralCategories)
+	}
+	fmt.Printf("Общее время выполнения: %v\n", summary.TotalDuration)
+	fmt.Printf("Среднее время запроса: %v\n", summary.AverageLatency)
+	if summary.SuccessCount > 0 {
+		fmt.Printf("Перцентили времени запроса: p50 %v, p90 %v, p99 %v\n", summary.LatencyP50, summary.LatencyP90, summary.LatencyP99)
+	}
+	for _, slo := range summary.SLOs {
+		fmt.Printf("Запросов не дольше %v: %.1f%% (%d из %d)", slo.Threshold, slo.Fraction*100, slo.Under, slo.Requests)
+		switch {
+		case slo.Missed:
+			fmt.Printf(", цель %.1f%% не выполнена\n", slo.Target*100)
+		case slo.Target > 0:
+			fmt.Printf(", цель %.1f%% выполнена\n", slo.Target*100)
+		default:
+			fmt.Printf("\n")
+		}
+	}
+	fmt.Printf("Запросов в секунду: %.2f\n", summary.RequestsPerSecond)
+	if summary.ClosedRequests > 0 {
+		fmt.Printf("С Connection: close: %d успешных, среднее %v против %v с keep-alive\n",
+			summary.ClosedRequests, summary.ClosedAverageLatency, summary.KeepAliveAverageLatency)
+	}
+	if summary.NewConnections > 0 {
+		fmt.Printf("Новых TCP-соединений: %d (%.2f в секунду), запросов на соединение: %.1f\n",
+			summary.NewConnections, summary.ConnectionsPerSecond, summary.RequestsPerConnection)
+	}
+	if summary.ArrivalMode != "" && summary.ArrivalMean > 0 {
+		fmt.Printf("Поступление запросов (%s, целевой интервал %v): средний интервал %v (%.2f в секунду), p50 %v, p90 %v, p99 %v\n",
+			summary.ArrivalMode, summary.ArrivalTargetMean, summary.ArrivalMean.Round(time.Microsecond), 1/summary.ArrivalMean.Seconds(),
+			summary.ArrivalP50.Round(time.Microsecond), summary.ArrivalP90.Round(time.Microsecond), summary.ArrivalP99.Round(time.Microsecond))
+	}
+	if len(summary.Bursts) > 0 {
+		fmt.Printf("Всплески по %d запросов каждые %v:\n", summary.BurstSize, summary.BurstInterval)
+		for _, burst := range summary.Bursts {
+			fmt.Printf("  #%d: %d запросов, ошибок %d, за %v, p50 %v, p99 %v\n", burst.Index+1, burst.Requests, burst.Failures,
+				burst.Span.Round(time.Millisecond), burst.P50.Round(time.Microsecond), burst.P99.Round(time.Microsecond))
+		}
+	}
+	if len(summary.StatusLatencies) > 1 {
+		fmt.Printf("Время по статусам:\n")
+		for _, status := range summary.StatusLatencies {
+			fmt.Printf("  %d: %d запросов, среднее %v, p50 %v, p99 %v\n", status.StatusCode, status.Count, status.Average, status.P50, status.P99)
+		}
+	}
+	if summary.ImagesSent > 0 {
+		fmt.Printf("Различных изображений (по содержимому): %d из %d отправленных\n", summary.DistinctImages, summary.ImagesSent)
+	}
+	if summary.TransferEncoding == "chunked" {
+		if rejected := summary.FailureCategories[categoryChunkedRejected]; rejected > 0 {
+			fmt.Printf("Chunked-загрузки отклонены с 411 Length Required: %d из %d\n", rejected, summary.TotalRequests)
+		} else if summary.SuccessCount > 0 {
+			fmt.Printf("Сервер принимает chunked-загрузки: %d успешных\n", summary.SuccessCount)
+		}
+	}
+	if len(summary.FolderShares) > 0 {
+		fmt.Printf("Изображения по папкам:\n")
+		for _, folder := range summary.FolderShares {
+			fmt.Printf("  %s: %d (%.1f%%, задано %.1f%%)\n", folder.Folder, folder.Images, folder.Share*100, folder.TargetShare*100)
+		}
+	}
+	if len(summary.WorkerImages) > 0 {
+		fmt.Printf("Изображения по воркерам:\n")
+		workerIDs := make([]int, 0, len(summary.WorkerImages))
+		for workerID := range summary.WorkerImages {
+			workerIDs = append(workerIDs, workerID)
+		}
+		sort.Ints(workerIDs)
+		for _, workerID := range workerIDs {
+			images := summary.WorkerImages[workerID]
+			names := make([]string, 0, len(images))
+			for name := range images {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			parts := make([]string, len(names))
+			for i, name := range names {
+				parts[i] = fmt.Sprintf("%s (%d)", name, images[name])
+			}
+			fmt.Printf("  воркер %d: %s\n", workerID, strings.Join(parts, ", "))
+		}
+	}
+	if len(summary.PhaseBreakdown) > 0 {
+		printPhaseBreakdown(summary.PhaseBreakdown)
+	}
+	if len(summary.RequestPhases) > 0 {
+		fmt.Printf("Время по частям запроса (отправка тела, ожидание сервера, чтение ответа):\n")
+		for _, phase := range summary.RequestPhases {
+			fmt.Printf("  %s: среднее %v, p50 %v, p99 %v\n", phase.Phase, phase.Average, phase.P50, phase.P99)
+		}
+	}
+	if summary.ConnWaitSamples > 0 {
+		fmt.Printf("Ожидание соединения из пула: среднее %v, максимум %v\n", summary.AverageConnWait, summary.MaxConnWait)
+	}
+	if len(summary.SizeDistribution) > 0 {
+		fmt.Printf("Распределение размеров отправленных изображений:\n")
+		for _, bucket := range summary.SizeDistribution {
+			fmt.Printf("  %s: %d\n", bucket.Label, bucket.Count)
+		}
+	}
+	if len(summary.ProblemImages) > 0 {
+		fmt.Printf("Изображения с повышенной долей ошибок:\n")
+		for _, image := range summary.ProblemImages {
+			fmt.Printf("  %s: %d из %d (%.0f%%)\n", image.Image, image.Failures, image.Requests, image.FailureRate*100)
+		}
+	}
+	if len(summary.SlowestRequests) > 0 {
+		fmt.Printf("Самые медленные запросы:\n")
+		for _, request := range summary.SlowestRequests {
+			fmt.Printf("  #%d %s: %v (статус %d)\n", request.RequestNum, request.ImageName, request.Duration, request.StatusCode)
+		}
+	}
+	if len(summary.Sweep) > 0 {
+		fmt.Printf("Перебор параллельности:\n")
+		fmt.Printf("  %11s %9s %7s %10s %12s\n", "параллельн.", "запросов", "ошибок", "RPS", "p99")
+		for _, level := range summary.Sweep {
+			fmt.Printf("  %11d %9d %7d %10.2f %12v\n", level.Concurrency, level.Requests, level.Failures, level.RPS, level.P99.Round(time.Microsecond))
+		}
+		if summary.SweepPlateau > 0 {
+			fmt.Printf("RPS перестаёт расти при параллельности %d\n", summary.SweepPlateau)
+		}
+		if summary.SweepP99Knee > 0 {
+			fmt.Printf("p99 ухудшается более чем в %.0f раза при параллельности %d\n", sweepP99Degradation, summary.SweepP99Knee)
+		}
+	}
+	if summary.ConfiguredConcurrency > 0 {
+		fmt.Printf("Фактическая параллельность: в среднем %.2f из %d (%.0f%%), максимум %d\n",
+			summary.AverageConcurrency, summary.ConfiguredConcurrency,
+			summary.AverageConcurrency/float64(summary.ConfiguredConcurrency)*100, summary.MaxConcurrency)
+	}
+	if len(summary.HostConcurrency) > 0 {
+		fmt.Printf("Параллельность по хостам:\n")
+		for _, host := range summary.HostConcurrency {
+			fmt.Printf("  %s: в среднем %.2f, максимум %d из %d\n", host.Host, host.Average, host.Max, host.Limit)
+		}
+	}
+	if summary.QueueCapacity > 0 {
+		fmt.Printf("Очередь: в среднем %.1f из %d, максимум %d, заполнена %.0f%% времени\n",
+			summary.QueueAverage, summary.QueueCapacity, summary.QueueMax, summary.QueueFullFraction*100)
+		if summary.QueueFullFraction > 0.5 {
+			fmt.Printf("  очередь почти всегда полна: узкое место на стороне сервера\n")
+		} else if summary.QueueAverage < 1 {
+			fmt.Printf("  очередь почти всегда пуста: узкое место на стороне генератора запросов\n")
+		}
+	}
+	if summary.Format == "ndjson" || summary.BatchSize > 1 {
+		fmt.Printf("Формат %s, изображений в запросе: %d, отправлено изображений: %d\n", summary.Format, summary.BatchSize, summary.ImagesSent)
+		fmt.Printf("Среднее время пакета: %v, на изображение: %v\n", summary.AverageLatency, summary.AverageLatency/time.Duration(summary.BatchSize))
+		if summary.PayloadBytes > 0 {
+			fmt.Printf("Накладные расходы кодирования: %s изображений -> %s отправлено (%+.1f%%)\n", formatBytes(summary.PayloadBytes),
+				formatBytes(summary.BytesSent), (float64(summary.BytesSent)/float64(summary.PayloadBytes)-1)*100)
+		}
+	}
+	if summary.WSConnections > 0 {
+		fmt.Printf("WebSocket: открыто соединений %d, переиспользований %d\n", summary.WSConnections, summary.WSReuses)
+	}
+	if len(summary.MalformedResponses) > 0 {
+		fmt.Printf("Ответы сервера на некорректные загрузки (0 - ошибка соединения):\n")
+		corruptions := make([]string, 0, len(summary.MalformedResponses))
+		for corruption := range summary.MalformedResponses {
+			corruptions = append(corruptions, corruption)
+		}
+		sort.Strings(corruptions)
+		for _, corruption := range corruptions {
+			codes := make([]int, 0, len(summary.MalformedResponses[corruption]))
+			for code := range summary.MalformedResponses[corruption] {
+				codes = append(codes, code)
+			}
+			sort.Ints(codes)
+			parts := make([]string, len(codes))
+			for i, code := range codes {
+				parts[i] = fmt.Sprintf("%d (%d)", code, summary.MalformedResponses[corruption][code])
+			}
+			fmt.Printf("  %s: %s\n", corruption, strings.Join(parts, ", "))
+		}
+	}
+	if summary.VerifiedCount > 0 {
+		fmt.Printf("Проверено через GET: %d, ошибок проверки: %d\n", summary.VerifiedCount, summary.VerifyFailures)
+	}
+	if summary.BreakerOpenCount > 0 {
+		fmt.Printf("Срабатываний предохранителя: %d, пауза всего: %v\n", summary.BreakerOpenCount, summary.BreakerOpenTime.Round(time.Millisecond))
+	}
+	if summary.ChunkCount > 0 {
+		fmt.Printf("Загружено частей: %d\n", summary.ChunkCount)
+		fmt.Printf("Среднее время загрузки части: %v\n", summary.AverageChunkTime)
+	}
+
+	if summary.MonitoringAvailable {
+		printMemory(summary)
+	}
+	switch summary.MemoryVerdict {
+	case memoryStable:
+		fmt.Printf("Проверка памяти: OK, память вернулась к исходному уровню\n")
+	case memoryLeaked:
+		fmt.Printf("Проверка памяти: ПРОВАЛ, после паузы память выше исходной на %.2f MB (возможна утечка)\n",
+			float64(memoryDelta(summary.InitialMemory, summary.SettledMemory))/1024/1024)
+	case memoryUnverified:
+		fmt.Printf("Проверка памяти: ПРОВАЛ, память после паузы не измерена\n")
+	}
+
+	fmt.Printf("\n=== Сетевой трафик ===\n")
+	fmt.Printf("Отправлено клиентом: %s\n", formatBytes(summary.BytesSent))
+	if summary.NetworkAvailable {
+		fmt.Printf("Получено контейнером (RX): %s\n", formatBytes(int64(summary.NetworkRxBytes)))
+		fmt.Printf("Отправлено контейнером (TX): %s\n", formatBytes(int64(summary.NetworkTxBytes)))
+		if summary.BytesSent > 0 {
+			ratio := float64(summary.NetworkRxBytes) / float64(summary.BytesSent)
+			if ratio < 1-networkDiscrepancyThreshold || ratio > 1+networkDiscrepancyThreshold {
+				fmt.Printf("Внимание: RX контейнера отличается от отправленного клиентом в %.2f раза (буферизация прокси или неудачные загрузки?)\n", ratio)
+			}
+		}
+	} else {
+		fmt.Printf("Сетевая статистика контейнера недоступна\n")
+	}
+	return nil
+}
+
+func printMemory(summary Summary) {
+	memoryDifference := memoryDelta(summary.InitialMemory, summary.FinalMemory)
+
+	fmt.Printf("\n=== Использование памяти ===\n")
+	fmt.Printf("Начальное использование памяти: %.2f MB\n", float64(summary.InitialMemory)/1024/1024)
+	fmt.Printf("Конечное использование памяти: %.2f MB\n", float64(summary.FinalMemory)/1024/1024)
+	fmt.Printf("Разница в использовании памяти: %.2f MB\n", float64(memoryDifference)/1024/1024)
+	if summary.MemorySamples > 0 {
+		fmt.Printf("Замеров памяти во время теста: %d\n", summary.MemorySamples)
+		fmt.Printf("Пик: %.2f MB, среднее: %.2f MB\n", float64(summary.PeakMemory)/1024/1024, float64(summary.AverageMemory)/1024/1024)
+		fmt.Printf("p50: %.2f MB, p90: %.2f MB, p99: %.2f MB\n",
+			float64(summary.MemoryP50)/1024/1024, float64(summary.MemoryP90)/1024/1024, float64(summary.MemoryP99)/1024/1024)
+		if len(summary.MemoryPhases) > 1 {
+			fmt.Printf("Память по фазам:\n")
+			for _, phase := range summary.MemoryPhases {
+				fmt.Printf("  %s: %d замеров, мин %.2f MB, среднее %.2f MB, макс %.2f MB\n", phase.Phase, phase.Samples,
+					float64(phase.Min)/1024/1024, float64(phase.Average)/1024/1024, float64(phase.Max)/1024/1024)
+			}
+		}
+	}
+	if summary.SettledMemory > 0 {
+		settledDifference := memoryDelta(summary.InitialMemory, summary.SettledMemory)
+		fmt.Printf("Память после паузы %v: %.2f MB\n", summary.Cooldown, float64(summary.SettledMemory)/1024/1024)
+		fmt.Printf("Разница после паузы: %.2f MB\n", float64(settledDifference)/1024/1024)
+	}
+}
+
+// formatAttempts lists an attempt histogram in attempt order, as in
+// "1: 950, 2: 40, 3: 5".
+func formatAttempts(counts map[int]int) string {
+	if len(counts) == 0 {
+		return "нет"
+	}
+	attempts := make([]int, 0, len(counts))
+	for attempt := range counts {
+		attempts = append(attempts, attempt)
+	}
+	sort.Ints(attempts)
+	parts := make([]string, 0, len(attempts))
+	for _, attempt := range attempts {
+		parts = append(parts, fmt.Sprintf("%d: %d", attempt, counts[attempt]))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func printCategories(counts map[string]int) {
+	categories := make([]string, 0, len(counts))
+	for category := range counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for _, category := range categories {
+		fmt.Printf("  %s: %d\n", category, counts[category])
+	}
+}
+
+type jsonReporter struct {
+	path string
+}
+
+func (reporter *jsonReporter) RecordRequest(result RequestResult) {}
+
+func (reporter *jsonReporter) Finish(summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding JSON summary: %v", err)
+	}
+
+	if err := os.WriteFile(reporter.path, data, 0644); err != nil {
+		return fmt.Errorf("error writing JSON summary: %v", err)
+	}
+	return nil
+}
+
+// csvReporter repeats the run metadata on every row, so rows from several
+// runs can be concatenated and still grouped.
+type csvReporter struct {
+	file     *os.File
+	writer   *csv.Writer
+	metadata []string
+	mutex    sync.Mutex
+}
+
+func newCSVReporter(path string, metadata RunMetadata) (*csvReporter, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating CSV file: %v", err)
+	}
+
+	header := []string{"request_num", "image", "status", "duration_ms", "success", "category", "error", "trace_id",
+		"config_hash", "started_at", "hostname", "tool_version"}
+	values := []string{metadata.ConfigHash, metadata.StartedAt.Format(time.RFC3339), metadata.Hostname, metadata.ToolVersion}
+	// One label_<key> column per -label keeps them filterable in a spreadsheet.
+	for _, key := range labelKeys(metadata.Labels) {
+		header = append(header, "label_"+key)
+		values = append(values, metadata.Labels[key])
+	}
+
+	writer := csv.NewWriter(file)
+	writer.Write(header)
+
+	return &csvReporter{file: file, writer: writer, metadata: values}, nil
+}
+
+func (reporter *csvReporter) RecordRequest(result RequestResult) {
+	errText := ""
+	if result.Err != nil {
+		errText = result.Err.Error()
+	}
+
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	reporter.writer.Write(append([]string{
+		strconv.Itoa(result.RequestNum),
+		result.ImageName,
+		strconv.Itoa(result.StatusCode),
+		strconv.FormatFloat(float64(result.Duration)/float64(time.Millisecond), 'f', 3, 64),
+		strconv.FormatBool(result.Success),
+		result.Category,
+		errText,
+		result.TraceID,
+	}, reporter.metadata...))
+}
+
+func (reporter *csvReporter) Finish(summary Summary) error {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+
+	reporter.writer.Flush()
+	if err := reporter.writer.Error(); err != nil {
+		reporter.file.Close()
+		return fmt.Errorf("error writing CSV file: %v", err)
+	}
+	return reporter.file.Close()
+}
+
+type failuresReporter struct {
+	file  *os.File
+	mutex sync.Mutex
+}
+
+func newFailuresReporter(path string) (*failuresReporter, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening failures file: %v", err)
+	}
+	return &failuresReporter{file: file}, nil
+}
+
+func (reporter *failuresReporter) RecordRequest(result RequestResult) {
+	if result.Success || result.Neutral {
+		return
+	}
+
+	message := ""
+	if result.Err != nil {
+		message = strings.ReplaceAll(result.Err.Error(), "\n", " ")
+	}
+
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	fmt.Fprintf(reporter.file, "%d\t%s\t%s\t%s\t%s\n", result.RequestNum, result.ImageName, result.Category, message, result.TraceID)
+}
+
+func (reporter *failuresReporter) Finish(summary Summary) error {
+	reporter.mutex.Lock()
+	defer reporter.mutex.Unlock()
+	return reporter.file.Close()
+}
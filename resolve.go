@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+)
+
+// hostResolver pins host:port pairs to fixed addresses like curl --resolve.
+// Only the dialed address changes, so the Host header and TLS SNI still
+// carry the original name and routing behind a VIP keeps working.
+type hostResolver struct {
+	overrides map[string]string
+	dial      dialFunc
+
+	mutex sync.Mutex
+	used  map[string]int
+}
+
+type dialFunc func(ctx context.Context, network string, addr string) (net.Conn, error)
+
+// newHostResolver dials the pinned addresses through dial, or a default
+// dialer when it is nil.
+func newHostResolver(overrides map[string]string, dial dialFunc) *hostResolver {
+	if dial == nil {
+		dial = defaultDialer().DialContext
+	}
+	return &hostResolver{
+		overrides: overrides,
+		dial:      dial,
+		used:      make(map[string]int),
+	}
+}
+
+func (resolver *hostResolver) dialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if target, ok := resolver.overrides[addr]; ok {
+		resolver.mutex.Lock()
+		resolver.used[addr+" -> "+target]++
+		resolver.mutex.Unlock()
+		addr = target
+	}
+	return resolver.dial(ctx, network, addr)
+}
+
+// dials reports how many connections went to each pinned address.
+func (resolver *hostResolver) dials() map[string]int {
+	resolver.mutex.Lock()
+	defer resolver.mutex.Unlock()
+	counts := make(map[string]int, len(resolver.used))
+	for pinning, count := range resolver.used {
+		counts[pinning] = count
+	}
+	return counts
+}
+
+// parseResolves reads host:port:addr values; addr may be an IPv6 literal
+// in brackets.
+func parseResolves(values []string) (map[string]string, error) {
+	overrides := make(map[string]string)
+	for _, value := range values {
+		parts := strings.SplitN(value, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("expected host:port:addr, got %q", value)
+		}
+		host, port, addr := parts[0], parts[1], strings.Trim(parts[2], "[]")
+		if net.ParseIP(addr) == nil {
+			return nil, fmt.Errorf("%q is not an IP address in %q", addr, value)
+		}
+		overrides[net.JoinHostPort(host, port)] = net.JoinHostPort(addr, port)
+	}
+	return overrides, nil
+}
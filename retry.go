@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// retryBudgetBurst caps the banked retry tokens, so a long healthy stretch
+// can't save up enough for a storm once the server starts failing.
+const retryBudgetBurst = 10
+
+// retryBudget is a token bucket: every first attempt deposits ratio tokens
+// and every retry spends one, so retries stay near ratio of the requests no
+// matter how many of them fail.
+type retryBudget struct {
+	ratio  float64
+	mutex  sync.Mutex
+	tokens float64
+	denied int
+}
+
+func newRetryBudget(ratio float64) *retryBudget {
+	return &retryBudget{ratio: ratio}
+}
+
+func (budget *retryBudget) deposit() {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	budget.tokens = min(budget.tokens+budget.ratio, retryBudgetBurst)
+}
+
+func (budget *retryBudget) withdraw() bool {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	if budget.tokens < 1 {
+		budget.denied++
+		return false
+	}
+	budget.tokens--
+	return true
+}
+
+func (budget *retryBudget) deniedCount() int {
+	budget.mutex.Lock()
+	defer budget.mutex.Unlock()
+	return budget.denied
+}
+
+// makeRequestWithRetries resends transient failures up to -retries times
+// with exponential backoff. A nil budget allows every retry; a drained one
+// fails the request with its last result right away.
+func makeRequestWithRetries(ctx context.Context, cfg *Config, client *http.Client, job uploadJob, bearerToken string, budget *retryBudget) RequestResult {
+	if budget != nil {
+		budget.deposit()
+	}
+
+	backoff := cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		result := safeMakeRequest(ctx, cfg, client, job, bearerToken)
+		result.Attempts = attempt
+		if result.Success || result.Neutral || !isTransient(result) || attempt > cfg.Retries {
+			return result
+		}
+		if budget != nil && !budget.withdraw() {
+			return result
+		}
+
+		select {
+		case <-ctx.Done():
+			return result
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// attemptHistogram counts requests by the attempt they ended on, separately
+// for those that succeeded and those that failed for good. A success past
+// the first attempt is a failure the retries hid.
+func (stats *RequestStats) attemptHistogram() (successes map[int]int, failures map[int]int) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return maps.Clone(stats.successAttempts), maps.Clone(stats.failureAttempts)
+}
@@ -0,0 +1,31 @@
+package main
+
+import "time"
+
+// maxRuntimeGrace is how long canceled requests get to wind down after
+// -max-runtime before the workers still stuck in them are abandoned.
+const maxRuntimeGrace = 10 * time.Second
+
+// startRuntimeCap calls onCap once limit has passed, which should cancel the
+// run. If the run still hasn't finished maxRuntimeGrace later, onStuck is
+// called to report what has completed and exit without the stuck workers.
+func startRuntimeCap(limit time.Duration, onCap func(), onStuck func()) func() {
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-done:
+			return
+		case <-time.After(limit):
+		}
+		onCap()
+
+		select {
+		case <-done:
+			return
+		case <-time.After(maxRuntimeGrace):
+		}
+		onStuck()
+	}()
+
+	return func() { close(done) }
+}
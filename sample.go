@@ -0,0 +1,54 @@
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// durationSample holds the durations percentiles are taken from. Without a
+// limit it keeps every one; with a limit, as -forever sets, it keeps a
+// uniform random sample of that many (reservoir sampling), so memory stays
+// bounded however long the run goes. count and total always cover every
+// duration added.
+type durationSample struct {
+	values []time.Duration
+	count  int
+	total  time.Duration
+}
+
+func (sample *durationSample) add(duration time.Duration, limit int) {
+	sample.count++
+	sample.total += duration
+	if limit <= 0 || len(sample.values) < limit {
+		sample.values = append(sample.values, duration)
+		return
+	}
+	if i := rand.IntN(sample.count); i < limit {
+		sample.values[i] = duration
+	}
+}
+
+func (sample *durationSample) average() time.Duration {
+	if sample.count == 0 {
+		return 0
+	}
+	return sample.total / time.Duration(sample.count)
+}
+
+// atMost estimates how many of the durations added were at most threshold,
+// scaling the sample's share up to the full count.
+func (sample *durationSample) atMost(threshold time.Duration) int {
+	if len(sample.values) == 0 {
+		return 0
+	}
+	under := 0
+	for _, value := range sample.values {
+		if value <= threshold {
+			under++
+		}
+	}
+	if len(sample.values) == sample.count {
+		return under
+	}
+	return int(float64(under) / float64(len(sample.values)) * float64(sample.count))
+}
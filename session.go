@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// cookieSession logs in once before the run and shares the resulting
+// cookies with every worker through the client's jar. A 401 triggers one
+// fresh login, however many workers hit it at the same time.
+type cookieSession struct {
+	loginURL string
+	form     url.Values
+	jar      http.CookieJar
+	client   *http.Client
+
+	mutex     sync.Mutex
+	lastLogin time.Time
+	logins    int
+}
+
+// newCookieSession logs in and installs the session on client: the jar for
+// the cookies and a transport that refreshes the session on 401.
+func newCookieSession(cfg *Config, client *http.Client) (*cookieSession, error) {
+	jar, err := cookiejar.New(nil)
+	if err != nil {
+		return nil, err
+	}
+	form := url.Values{}
+	for _, field := range cfg.loginFields {
+		form.Add(field.key, field.value)
+	}
+
+	session := &cookieSession{
+		loginURL: cfg.LoginURL,
+		form:     form,
+		jar:      jar,
+		// The login client skips the refreshing transport so a failed
+		// login can't recurse.
+		client: &http.Client{Transport: client.Transport, Jar: jar, Timeout: requestTimeout},
+	}
+	if err := session.login(); err != nil {
+		return nil, err
+	}
+
+	client.Jar = jar
+	client.Transport = &sessionTransport{base: client.Transport, session: session}
+	return session, nil
+}
+
+func (session *cookieSession) login() error {
+	resp, err := session.client.PostForm(session.loginURL, session.form)
+	if err != nil {
+		return fmt.Errorf("login request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("login returned status %d", resp.StatusCode)
+	}
+	if len(session.jar.Cookies(resp.Request.URL)) == 0 {
+		fmt.Printf("Warning: login at %s set no cookies\n", session.loginURL)
+	}
+	session.lastLogin = time.Now()
+	session.logins++
+	return nil
+}
+
+// refresh logs in again unless another worker already did so after the
+// rejected request was sent.
+func (session *cookieSession) refresh(sentAt time.Time) error {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	if session.lastLogin.After(sentAt) {
+		return nil
+	}
+	return session.login()
+}
+
+func (session *cookieSession) loginCount() int {
+	session.mutex.Lock()
+	defer session.mutex.Unlock()
+	return session.logins
+}
+
+type sessionTransport struct {
+	base    http.RoundTripper
+	session *cookieSession
+}
+
+// RoundTrip resends a request once after refreshing the session on 401.
+// Streamed bodies can't be replayed, so those 401s are returned as they are.
+func (transport *sessionTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sentAt := time.Now()
+	resp, err := transport.base.RoundTrip(req)
+	if err != nil || resp.StatusCode != http.StatusUnauthorized || (req.Body != nil && req.GetBody == nil) {
+		return resp, err
+	}
+
+	if err := transport.session.refresh(sentAt); err != nil {
+		fmt.Printf("Warning: session refresh failed: %v\n", err)
+		return resp, nil
+	}
+	retry := req.Clone(req.Context())
+	if req.GetBody != nil {
+		if retry.Body, err = req.GetBody(); err != nil {
+			return resp, nil
+		}
+	}
+	// The client added the old cookies before calling the transport.
+	retry.Header.Del("Cookie")
+	var cookies []string
+	for _, cookie := range transport.session.jar.Cookies(req.URL) {
+		cookies = append(cookies, cookie.Name+"="+cookie.Value)
+	}
+	if len(cookies) > 0 {
+		retry.Header.Set("Cookie", strings.Join(cookies, "; "))
+	}
+
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body.Close()
+	return transport.base.RoundTrip(retry)
+}
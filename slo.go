@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type sloTarget struct {
+	threshold time.Duration
+	target    float64 // required fraction under threshold, 0 reports only
+}
+
+// parseSLO reads a -slo value: a threshold such as 200ms, optionally with
+// the percentage that must stay under it, as in 200ms:99.
+func parseSLO(value string) (sloTarget, error) {
+	thresholdText, targetText, hasTarget := strings.Cut(value, ":")
+	threshold, err := time.ParseDuration(thresholdText)
+	if err != nil || threshold <= 0 {
+		return sloTarget{}, fmt.Errorf("expected a positive duration such as 200ms, got %q", thresholdText)
+	}
+	slo := sloTarget{threshold: threshold}
+	if hasTarget {
+		percent, err := strconv.ParseFloat(strings.TrimSuffix(targetText, "%"), 64)
+		if err != nil || percent <= 0 || percent > 100 {
+			return sloTarget{}, fmt.Errorf("expected a percentage between 0 and 100 after the threshold, got %q", targetText)
+		}
+		slo.target = percent / 100
+	}
+	return slo, nil
+}
+
+type SLOCompliance struct {
+	Threshold time.Duration `json:"threshold_ns"`
+	Requests  int           `json:"requests"`
+	Under     int           `json:"under"`
+	Fraction  float64       `json:"fraction"`
+	Target    float64       `json:"target,omitempty"`
+	Missed    bool          `json:"missed,omitempty"`
+}
+
+// sloCompliance counts the successful requests that finished within each
+// threshold. Timed-out requests count as over every threshold; leaving them
+// out would make a server that hangs look compliant. Other failures and
+// neutral outcomes have no meaningful latency and are left out.
+func (stats *RequestStats) sloCompliance(targets []sloTarget) []SLOCompliance {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	requests := stats.successCount + stats.failureCategories[categoryTimeout]
+	compliance := make([]SLOCompliance, 0, len(targets))
+	for _, slo := range targets {
+		result := SLOCompliance{Threshold: slo.threshold, Requests: requests, Target: slo.target}
+		result.Under = stats.latencies.atMost(slo.threshold)
+		if requests > 0 {
+			result.Fraction = float64(result.Under) / float64(requests)
+		}
+		result.Missed = slo.target > 0 && result.Fraction < slo.target
+		compliance = append(compliance, result)
+	}
+	return compliance
+}
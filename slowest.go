@@ -0,0 +1,50 @@
+package main
+
+import (
+	"container/heap"
+	"sort"
+	"time"
+)
+
+type SlowRequest struct {
+	RequestNum int           `json:"request"`
+	ImageName  string        `json:"image"`
+	Duration   time.Duration `json:"duration_ns"`
+	StatusCode int           `json:"status"`
+}
+
+// slowestHeap is a min-heap, so the fastest of the retained requests is evicted first.
+type slowestHeap []SlowRequest
+
+func (h slowestHeap) Len() int           { return len(h) }
+func (h slowestHeap) Less(i, j int) bool { return h[i].Duration < h[j].Duration }
+func (h slowestHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+
+func (h *slowestHeap) Push(x any) { *h = append(*h, x.(SlowRequest)) }
+
+func (h *slowestHeap) Pop() any {
+	old := *h
+	last := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return last
+}
+
+func (h *slowestHeap) offer(request SlowRequest, limit int) {
+	if limit <= 0 {
+		return
+	}
+	if h.Len() < limit {
+		heap.Push(h, request)
+		return
+	}
+	if request.Duration > (*h)[0].Duration {
+		(*h)[0] = request
+		heap.Fix(h, 0)
+	}
+}
+
+func (h slowestHeap) sorted() []SlowRequest {
+	requests := append([]SlowRequest(nil), h...)
+	sort.Slice(requests, func(i, j int) bool { return requests[i].Duration > requests[j].Duration })
+	return requests
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// snapshotReporter keeps -report-file current for dashboards that poll it:
+// startSnapshotWriter replaces it with a partial summary every interval and
+// Finish with the final one. Writes go through a temporary file and a
+// rename, so a reader never sees half a snapshot.
+type snapshotReporter struct {
+	path string
+}
+
+func (reporter *snapshotReporter) RecordRequest(result RequestResult) {}
+
+func (reporter *snapshotReporter) Finish(summary Summary) error {
+	return reporter.write(summary)
+}
+
+func (reporter *snapshotReporter) write(summary Summary) error {
+	data, err := json.MarshalIndent(summary, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding report snapshot: %v", err)
+	}
+	temporary := reporter.path + ".tmp"
+	if err := os.WriteFile(temporary, data, 0644); err != nil {
+		return fmt.Errorf("error writing report snapshot: %v", err)
+	}
+	if err := os.Rename(temporary, reporter.path); err != nil {
+		return fmt.Errorf("error writing report snapshot: %v", err)
+	}
+	return nil
+}
+
+// startSnapshotWriter writes a partial summary marked in_progress every
+// interval until the returned function is called.
+func startSnapshotWriter(reporter *snapshotReporter, interval time.Duration, stats *RequestStats, metadata RunMetadata, startTime time.Time) func() {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			summary := partialSummary(stats, metadata, startTime)
+			summary.InProgress = true
+			if err := reporter.write(summary); err != nil {
+				fmt.Printf("Warning: %v\n", err)
+			}
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+	}
+}
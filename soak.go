@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+type soakSnapshot struct {
+	completed int
+	successes int
+	failures  int
+	totalTime time.Duration
+}
+
+// takeSoakSnapshot reads the counters only; a full summary would sort every
+// latency so far while holding the lock the workers record under.
+func takeSoakSnapshot(stats *RequestStats) soakSnapshot {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	snapshot := soakSnapshot{
+		successes: stats.successCount,
+		failures:  stats.failureCount,
+		totalTime: stats.totalTime,
+	}
+	snapshot.completed = snapshot.successes + snapshot.failures
+	for _, count := range stats.neutralCategories {
+		snapshot.completed += count
+	}
+	return snapshot
+}
+
+// soakReporter prints interval totals to stdout, or appends them to
+// -soak-file. The file is opened by newSoakReporter, before the run starts.
+type soakReporter struct {
+	interval time.Duration
+	file     *os.File
+}
+
+func newSoakReporter(interval time.Duration, path string) (*soakReporter, error) {
+	soak := &soakReporter{interval: interval}
+	if path != "" {
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("error opening soak file: %v", err)
+		}
+		soak.file = file
+	}
+	return soak, nil
+}
+
+func (soak *soakReporter) start(stats *RequestStats, startTime time.Time) func() {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(soak.interval)
+		defer ticker.Stop()
+
+		var previous soakSnapshot
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			current := takeSoakSnapshot(stats)
+			elapsed := time.Since(startTime).Round(time.Second)
+			line := fmt.Sprintf("[soak %v] interval: %s | total: %s\n", elapsed,
+				formatSoakWindow(previous, current, soak.interval), formatSoakWindow(soakSnapshot{}, current, time.Since(startTime)))
+			// os.Stdout is looked up per line: the -tui dashboard swaps it
+			// after the soak reporter has started.
+			var out io.Writer = os.Stdout
+			if soak.file != nil {
+				out = soak.file
+			}
+			fmt.Fprint(out, line)
+			previous = current
+		}
+	}()
+
+	return func() {
+		close(done)
+		<-finished
+		if soak.file != nil {
+			soak.file.Close()
+		}
+	}
+}
+
+func formatSoakWindow(from soakSnapshot, to soakSnapshot, window time.Duration) string {
+	completed := to.completed - from.completed
+	successes := to.successes - from.successes
+	failures := to.failures - from.failures
+
+	var average time.Duration
+	if successes > 0 {
+		average = (to.totalTime - from.totalTime) / time.Duration(successes)
+	}
+
+	var failureRate float64
+	if completed > 0 {
+		failureRate = float64(failures) / float64(completed) * 100
+	}
+
+	return fmt.Sprintf("%d req, %.2f rps, %d failures (%.1f%%), avg %v",
+		completed, float64(completed)/window.Seconds(), failures, failureRate, average.Round(time.Millisecond))
+}
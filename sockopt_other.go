@@ -0,0 +1,9 @@
+//go:build !unix
+
+package main
+
+import "syscall"
+
+const reuseAddrSupported = false
+
+func reuseAddrControl(network string, address string, conn syscall.RawConn) error { return nil }
@@ -0,0 +1,20 @@
+//go:build unix
+
+package main
+
+import "syscall"
+
+const reuseAddrSupported = true
+
+// reuseAddrControl sets SO_REUSEADDR before the socket is bound, so a
+// source port still in TIME_WAIT can be bound again.
+func reuseAddrControl(network string, address string, conn syscall.RawConn) error {
+	var sockErr error
+	err := conn.Control(func(fd uintptr) {
+		sockErr = syscall.SetsockoptInt(int(fd), syscall.SOL_SOCKET, syscall.SO_REUSEADDR, 1)
+	})
+	if err != nil {
+		return err
+	}
+	return sockErr
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+type portRange struct {
+	start int
+	end   int
+}
+
+// parseSourcePorts reads a -source-ports start:end range, inclusive.
+func parseSourcePorts(value string) (*portRange, error) {
+	startText, endText, ok := strings.Cut(value, ":")
+	if !ok {
+		return nil, fmt.Errorf("expected start:end, got %q", value)
+	}
+	start, err := strconv.Atoi(startText)
+	if err != nil || start < 1 || start > 65535 {
+		return nil, fmt.Errorf("invalid start port %q", startText)
+	}
+	end, err := strconv.Atoi(endText)
+	if err != nil || end < start || end > 65535 {
+		return nil, fmt.Errorf("invalid end port %q", endText)
+	}
+	return &portRange{start: start, end: end}, nil
+}
+
+// sourceDialer binds each connection to a local port from -source-ports,
+// taking them in turn, and sets SO_REUSEADDR with -reuseaddr. A port that
+// can't be bound, typically because an earlier connection on it is still
+// in TIME_WAIT, is counted and the next one is tried.
+type sourceDialer struct {
+	ports  *portRange
+	dialer net.Dialer
+	next   atomic.Int64
+
+	mutex        sync.Mutex
+	opened       int
+	bindFailures int
+}
+
+func newSourceDialer(ports *portRange, reuseAddr bool) *sourceDialer {
+	source := &sourceDialer{
+		ports:  ports,
+		dialer: *defaultDialer(),
+	}
+	if reuseAddr {
+		source.dialer.Control = reuseAddrControl
+	}
+	return source
+}
+
+func (source *sourceDialer) dialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	if source.ports == nil {
+		conn, err := source.dialer.DialContext(ctx, network, addr)
+		source.count(err == nil, false)
+		return conn, err
+	}
+
+	span := int64(source.ports.end - source.ports.start + 1)
+	var lastErr error
+	for range span {
+		dialer := source.dialer
+		dialer.LocalAddr = &net.TCPAddr{Port: source.ports.start + int((source.next.Add(1)-1)%span)}
+		conn, err := dialer.DialContext(ctx, network, addr)
+		if err == nil || !isBindError(err) {
+			source.count(err == nil, false)
+			return conn, err
+		}
+		source.count(false, true)
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, fmt.Errorf("no source port in %d-%d could be bound: %w", source.ports.start, source.ports.end, lastErr)
+}
+
+func (source *sourceDialer) count(opened bool, bindFailed bool) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	if opened {
+		source.opened++
+	}
+	if bindFailed {
+		source.bindFailures++
+	}
+}
+
+func (source *sourceDialer) report() (opened int, bindFailures int) {
+	source.mutex.Lock()
+	defer source.mutex.Unlock()
+	return source.opened, source.bindFailures
+}
+
+func isBindError(err error) bool {
+	var syscallErr *os.SyscallError
+	return errors.As(err, &syscallErr) && syscallErr.Syscall == "bind"
+}
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS runs (
+	id                 INTEGER PRIMARY KEY AUTOINCREMENT,
+	started_at         TEXT NOT NULL,
+	config_hash        TEXT NOT NULL,
+	url                TEXT NOT NULL,
+	total_requests     INTEGER NOT NULL,
+	success_count      INTEGER NOT NULL,
+	failure_count      INTEGER NOT NULL,
+	requests_per_second REAL NOT NULL,
+	p99_ms             REAL NOT NULL,
+	error_rate         REAL NOT NULL,
+	memory_delta_bytes INTEGER,
+	hostname           TEXT NOT NULL DEFAULT '',
+	tool_version       TEXT NOT NULL DEFAULT '',
+	labels             TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS requests (
+	run_id      INTEGER NOT NULL REFERENCES runs(id),
+	request_num INTEGER NOT NULL,
+	image       TEXT NOT NULL,
+	status      INTEGER NOT NULL,
+	duration_ms REAL NOT NULL,
+	success     INTEGER NOT NULL,
+	category    TEXT NOT NULL,
+	trace_id    TEXT NOT NULL
+);`
+
+// sqliteReporter appends one row per run, and with -sqlite-requests one row
+// per request, so trends can be queried across runs.
+type sqliteReporter struct {
+	db          *sql.DB
+	metadata    RunMetadata
+	url         string
+	perRequest  bool
+	results     []RequestResult
+	resultsLock sync.Mutex
+}
+
+// sqliteMigrations add columns that databases created by older versions
+// lack; CREATE TABLE IF NOT EXISTS leaves existing tables alone.
+var sqliteMigrations = []string{
+	`ALTER TABLE runs ADD COLUMN hostname TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE runs ADD COLUMN tool_version TEXT NOT NULL DEFAULT ''`,
+	`ALTER TABLE runs ADD COLUMN labels TEXT NOT NULL DEFAULT ''`,
+}
+
+func newSQLiteReporter(cfg *Config, metadata RunMetadata) (*sqliteReporter, error) {
+	db, err := sql.Open("sqlite", cfg.SQLiteOutput)
+	if err != nil {
+		return nil, fmt.Errorf("error opening SQLite database: %v", err)
+	}
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("error creating SQLite tables: %v", err)
+	}
+	for _, migration := range sqliteMigrations {
+		if _, err := db.Exec(migration); err != nil && !strings.Contains(err.Error(), "duplicate column") {
+			db.Close()
+			return nil, fmt.Errorf("error upgrading SQLite tables: %v", err)
+		}
+	}
+
+	return &sqliteReporter{
+		db:         db,
+		metadata:   metadata,
+		url:        cfg.URL,
+		perRequest: cfg.SQLiteRequests,
+	}, nil
+}
+
+func (reporter *sqliteReporter) RecordRequest(result RequestResult) {
+	if !reporter.perRequest {
+		return
+	}
+	reporter.resultsLock.Lock()
+	defer reporter.resultsLock.Unlock()
+	reporter.results = append(reporter.results, result)
+}
+
+func (reporter *sqliteReporter) Finish(summary Summary) error {
+	defer reporter.db.Close()
+
+	var errorRate float64
+	if summary.TotalRequests > 0 {
+		errorRate = float64(summary.FailureCount) / float64(summary.TotalRequests)
+	}
+	var memoryDelta sql.NullInt64
+	if summary.MonitoringAvailable {
+		memoryDelta = sql.NullInt64{Int64: int64(summary.FinalMemory) - int64(summary.InitialMemory), Valid: true}
+	}
+
+	// Labels are stored as a JSON object, which SQLite's json_extract can
+	// filter on.
+	var labels string
+	if len(reporter.metadata.Labels) > 0 {
+		data, err := json.Marshal(reporter.metadata.Labels)
+		if err != nil {
+			return fmt.Errorf("error encoding run labels: %v", err)
+		}
+		labels = string(data)
+	}
+
+	tx, err := reporter.db.Begin()
+	if err != nil {
+		return fmt.Errorf("error writing SQLite results: %v", err)
+	}
+	defer tx.Rollback()
+
+	run, err := tx.Exec(`INSERT INTO runs (started_at, config_hash, url, total_requests, success_count, failure_count,
+		requests_per_second, p99_ms, error_rate, memory_delta_bytes, hostname, tool_version, labels)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		reporter.metadata.StartedAt.Format(time.RFC3339), reporter.metadata.ConfigHash, reporter.url, summary.TotalRequests,
+		summary.SuccessCount, summary.FailureCount, summary.RequestsPerSecond, durationMillis(summary.LatencyP99),
+		errorRate, memoryDelta, reporter.metadata.Hostname, reporter.metadata.ToolVersion, labels)
+	if err != nil {
+		return fmt.Errorf("error writing SQLite run row: %v", err)
+	}
+	runID, err := run.LastInsertId()
+	if err != nil {
+		return fmt.Errorf("error writing SQLite run row: %v", err)
+	}
+
+	if len(reporter.results) > 0 {
+		insert, err := tx.Prepare(`INSERT INTO requests (run_id, request_num, image, status, duration_ms, success, category, trace_id)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?)`)
+		if err != nil {
+			return fmt.Errorf("error writing SQLite request rows: %v", err)
+		}
+		defer insert.Close()
+
+		for _, result := range reporter.results {
+			if _, err := insert.Exec(runID, result.RequestNum, result.ImageName, result.StatusCode,
+				durationMillis(result.Duration), result.Success, result.Category, result.TraceID); err != nil {
+				return fmt.Errorf("error writing SQLite request rows: %v", err)
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error writing SQLite results: %v", err)
+	}
+	return nil
+}
+
+func durationMillis(duration time.Duration) float64 {
+	return float64(duration) / float64(time.Millisecond)
+}
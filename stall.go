@@ -0,0 +1,45 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+func startStallDetector(pool *workerPool, window time.Duration) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(checkInterval(window))
+		defer ticker.Stop()
+
+		lastCompleted := pool.completedCount()
+		lastProgress := time.Now()
+		stalled := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			completed := pool.completedCount()
+			if completed != lastCompleted {
+				if stalled {
+					fmt.Printf("Server recovered after a stall of %v\n", time.Since(lastProgress).Round(time.Second))
+					stalled = false
+				}
+				lastCompleted = completed
+				lastProgress = time.Now()
+				continue
+			}
+
+			inFlight, size := pool.inFlight(), pool.currentSize()
+			if !stalled && inFlight >= size && time.Since(lastProgress) >= window {
+				fmt.Printf("Warning: server appears stalled: %d of %d workers blocked with no completions for %v\n",
+					inFlight, size, time.Since(lastProgress).Round(time.Second))
+				stalled = true
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
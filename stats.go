@@ -0,0 +1,393 @@
+package main
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+type StatusLatency struct {
+	StatusCode int           `json:"status_code"`
+	Count      int           `json:"count"`
+	Average    time.Duration `json:"average_ns"`
+	P50        time.Duration `json:"p50_ns"`
+	P99        time.Duration `json:"p99_ns"`
+}
+
+type RequestStats struct {
+	successCount      int
+	failureCount      int
+	failureCategories map[string]int
+	neutralCategories map[string]int
+	totalTime         time.Duration
+	latencies         durationSample
+	statusLatencies   map[int]*durationSample
+	workerImages      map[int]map[string]int
+	bytesSent         int64
+	imagesSent        int
+	payloadBytes      int64
+	distinctImages    map[string]bool
+	folderImages      map[string]int
+	remoteFailures    map[string]int
+	protocols         map[string]int
+	newConnections    int
+	retries           int
+	successAttempts   map[int]int
+	failureAttempts   map[int]int
+	errorMessages     map[string]int
+	recorded          int
+	imageOutcomes     map[string]*imageOutcomes
+	connWaitCount     int
+	connWaitTotal     time.Duration
+	connWaitMax       time.Duration
+	uploadTimes       durationSample
+	breakdownTotal    requestBreakdown
+	breakdownCounts   []int
+	breakdownRequests int
+	closedCount       int
+	closedTime        time.Duration
+	serverTimes       durationSample
+	downloadTimes     durationSample
+	chunkCount        int
+	chunkTime         time.Duration
+	verifiedCount     int
+	malformed         map[string]map[int]int
+	sizeBuckets       []int
+	slowestLimit      int
+	sampleLimit       int
+	slowest           slowestHeap
+	mutex             sync.Mutex
+}
+
+func (stats *RequestStats) record(result RequestResult) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	stats.recorded++
+	stats.bytesSent += result.BytesSent
+	stats.imagesSent += result.Images
+	stats.payloadBytes += result.PayloadBytes
+	stats.chunkCount += result.Chunks
+	stats.chunkTime += result.ChunkTime
+	if result.Verified {
+		stats.verifiedCount++
+	}
+	if result.Corruption != "" {
+		if stats.malformed == nil {
+			stats.malformed = make(map[string]map[int]int)
+		}
+		if stats.malformed[result.Corruption] == nil {
+			stats.malformed[result.Corruption] = make(map[int]int)
+		}
+		stats.malformed[result.Corruption][result.StatusCode]++
+	}
+	stats.newConnections += result.NewConnections
+	if result.Attempts > 1 {
+		stats.retries += result.Attempts - 1
+	}
+	if result.Attempts > 0 && !result.Neutral {
+		if stats.successAttempts == nil {
+			stats.successAttempts = make(map[int]int)
+			stats.failureAttempts = make(map[int]int)
+		}
+		if result.Success {
+			stats.successAttempts[result.Attempts]++
+		} else {
+			stats.failureAttempts[result.Attempts]++
+		}
+	}
+	if result.ErrorMessage != "" {
+		if stats.errorMessages == nil {
+			stats.errorMessages = make(map[string]int)
+		}
+		stats.errorMessages[result.ErrorMessage]++
+	}
+	if result.Protocol != "" {
+		if stats.protocols == nil {
+			stats.protocols = make(map[string]int)
+		}
+		stats.protocols[result.Protocol]++
+	}
+	if result.Breakdown != (requestBreakdown{}) {
+		stats.breakdownTotal.add(result.Breakdown)
+		stats.breakdownRequests++
+		if stats.breakdownCounts == nil {
+			stats.breakdownCounts = make([]int, len(breakdownPhases))
+		}
+		for i, duration := range result.Breakdown.durations() {
+			if duration > 0 {
+				stats.breakdownCounts[i]++
+			}
+		}
+	}
+	if result.UploadTime > 0 {
+		stats.uploadTimes.add(result.UploadTime, stats.sampleLimit)
+		stats.serverTimes.add(result.ServerTime, stats.sampleLimit)
+		stats.downloadTimes.add(result.DownloadTime, stats.sampleLimit)
+	}
+	if result.ConnWait > 0 {
+		stats.connWaitCount++
+		stats.connWaitTotal += result.ConnWait
+		stats.connWaitMax = max(stats.connWaitMax, result.ConnWait)
+	}
+	if result.StatusCode > 0 {
+		if stats.statusLatencies == nil {
+			stats.statusLatencies = make(map[int]*durationSample)
+		}
+		if stats.statusLatencies[result.StatusCode] == nil {
+			stats.statusLatencies[result.StatusCode] = &durationSample{}
+		}
+		stats.statusLatencies[result.StatusCode].add(result.Duration, stats.sampleLimit)
+	}
+	if result.Duration > 0 {
+		stats.slowest.offer(SlowRequest{
+			RequestNum: result.RequestNum,
+			ImageName:  result.ImageName,
+			Duration:   result.Duration,
+			StatusCode: result.StatusCode,
+		}, stats.slowestLimit)
+	}
+
+	// Deliberately corrupted uploads are meant to fail, so they say nothing
+	// about the image itself.
+	if !result.Neutral && result.Corruption == "" && result.ImageName != "" {
+		if stats.imageOutcomes == nil {
+			stats.imageOutcomes = make(map[string]*imageOutcomes)
+		}
+		outcome := stats.imageOutcomes[result.ImageName]
+		if outcome == nil {
+			outcome = &imageOutcomes{}
+			stats.imageOutcomes[result.ImageName] = outcome
+		}
+		outcome.requests++
+		if !result.Success {
+			outcome.failures++
+		}
+	}
+
+	if result.Success {
+		stats.successCount++
+		stats.totalTime += result.Duration
+		if result.ForcedClose {
+			stats.closedCount++
+			stats.closedTime += result.Duration
+		}
+		stats.latencies.add(result.Duration, stats.sampleLimit)
+		return
+	}
+
+	if result.Neutral {
+		if stats.neutralCategories == nil {
+			stats.neutralCategories = make(map[string]int)
+		}
+		stats.neutralCategories[result.Category]++
+		return
+	}
+
+	stats.failureCount++
+	if stats.failureCategories == nil {
+		stats.failureCategories = make(map[string]int)
+	}
+	stats.failureCategories[result.Category]++
+	if result.Category == categoryConnection && result.RemoteAddr != "" {
+		if stats.remoteFailures == nil {
+			stats.remoteFailures = make(map[string]int)
+		}
+		stats.remoteFailures[result.RemoteAddr]++
+	}
+}
+
+func (stats *RequestStats) addWorkerImage(workerID int, imageName string) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if stats.workerImages == nil {
+		stats.workerImages = make(map[int]map[string]int)
+	}
+	if stats.workerImages[workerID] == nil {
+		stats.workerImages[workerID] = make(map[string]int)
+	}
+	stats.workerImages[workerID][imageName]++
+}
+
+// addSentImages tracks distinct image contents, so the same bytes under two
+// names count once.
+func (stats *RequestStats) addSentImages(images []ImageFile) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if stats.distinctImages == nil {
+		stats.distinctImages = make(map[string]bool)
+	}
+	for _, image := range images {
+		stats.distinctImages[image.hash] = true
+		if image.folder != "" {
+			if stats.folderImages == nil {
+				stats.folderImages = make(map[string]int)
+			}
+			stats.folderImages[image.folder]++
+		}
+	}
+}
+
+func (stats *RequestStats) phaseBreakdown() []PhaseShare {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if stats.breakdownRequests == 0 {
+		return nil
+	}
+	return phaseShares(stats.breakdownTotal, stats.breakdownCounts, stats.breakdownRequests)
+}
+
+func (stats *RequestStats) recordedCount() int {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	return stats.recorded
+}
+
+func (stats *RequestStats) folderCounts() map[string]int {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	counts := make(map[string]int, len(stats.folderImages))
+	for folder, count := range stats.folderImages {
+		counts[folder] = count
+	}
+	return counts
+}
+
+func (stats *RequestStats) addSentSize(size int) {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+	if stats.sizeBuckets == nil {
+		stats.sizeBuckets = make([]int, len(sizeBucketBounds)+1)
+	}
+	stats.sizeBuckets[sizeBucketIndex(size)]++
+}
+
+func (stats *RequestStats) summary(totalRequests int, totalDuration time.Duration) Summary {
+	stats.mutex.Lock()
+	defer stats.mutex.Unlock()
+
+	summary := Summary{
+		TotalRequests:     totalRequests,
+		SuccessCount:      stats.successCount,
+		FailureCount:      stats.failureCount,
+		FailureCategories: make(map[string]int, len(stats.failureCategories)),
+		TotalDuration:     totalDuration,
+		BytesSent:         stats.bytesSent,
+		ImagesSent:        stats.imagesSent,
+		DistinctImages:    len(stats.distinctImages),
+		PayloadBytes:      stats.payloadBytes,
+		RequestsPerSecond: float64(totalRequests) / totalDuration.Seconds(),
+		NewConnections:    stats.newConnections,
+		Retries:           stats.retries,
+	}
+	if stats.newConnections > 0 {
+		summary.ConnectionsPerSecond = float64(stats.newConnections) / totalDuration.Seconds()
+		summary.RequestsPerConnection = float64(totalRequests) / float64(stats.newConnections)
+	}
+	for category, count := range stats.failureCategories {
+		summary.FailureCategories[category] = count
+	}
+	if len(stats.remoteFailures) > 0 {
+		summary.ConnectionFailuresByRemote = make(map[string]int, len(stats.remoteFailures))
+		for remote, count := range stats.remoteFailures {
+			summary.ConnectionFailuresByRemote[remote] = count
+		}
+	}
+	if len(stats.protocols) > 0 {
+		summary.ConnectionProtocols = make(map[string]int, len(stats.protocols))
+		for protocol, count := range stats.protocols {
+			summary.ConnectionProtocols[protocol] = count
+		}
+	}
+	if len(stats.neutralCategories) > 0 {
+		summary.NeutralCategories = make(map[string]int, len(stats.neutralCategories))
+		for category, count := range stats.neutralCategories {
+			summary.NeutralCategories[category] = count
+		}
+	}
+	if stats.successCount > 0 {
+		summary.AverageLatency = stats.totalTime / time.Duration(stats.successCount)
+		marks := percentiles(stats.latencies.values, 50, 90, 99)
+		summary.LatencyP50, summary.LatencyP90, summary.LatencyP99 = marks[0], marks[1], marks[2]
+	}
+	if len(stats.workerImages) > 0 {
+		summary.WorkerImages = make(map[int]map[string]int, len(stats.workerImages))
+		for workerID, images := range stats.workerImages {
+			summary.WorkerImages[workerID] = make(map[string]int, len(images))
+			for name, count := range images {
+				summary.WorkerImages[workerID][name] = count
+			}
+		}
+	}
+	if stats.closedCount > 0 {
+		summary.ClosedRequests = stats.closedCount
+		summary.ClosedAverageLatency = stats.closedTime / time.Duration(stats.closedCount)
+		if keepAlive := stats.successCount - stats.closedCount; keepAlive > 0 {
+			summary.KeepAliveAverageLatency = (stats.totalTime - stats.closedTime) / time.Duration(keepAlive)
+		}
+	}
+	if stats.uploadTimes.count > 0 {
+		summary.RequestPhases = []RequestPhase{
+			newRequestPhase("upload", &stats.uploadTimes),
+			newRequestPhase("server", &stats.serverTimes),
+			newRequestPhase("download", &stats.downloadTimes),
+		}
+	}
+	if stats.connWaitCount > 0 {
+		summary.ConnWaitSamples = stats.connWaitCount
+		summary.AverageConnWait = stats.connWaitTotal / time.Duration(stats.connWaitCount)
+		summary.MaxConnWait = stats.connWaitMax
+	}
+	summary.SlowestRequests = stats.slowest.sorted()
+	summary.ProblemImages = problemImages(stats.imageOutcomes)
+	for code, durations := range stats.statusLatencies {
+		marks := percentiles(durations.values, 50, 99)
+		summary.StatusLatencies = append(summary.StatusLatencies, StatusLatency{
+			StatusCode: code,
+			Count:      durations.count,
+			Average:    durations.average(),
+			P50:        marks[0],
+			P99:        marks[1],
+		})
+	}
+	sort.Slice(summary.StatusLatencies, func(i, j int) bool {
+		return summary.StatusLatencies[i].StatusCode < summary.StatusLatencies[j].StatusCode
+	})
+	for i, count := range stats.sizeBuckets {
+		summary.SizeDistribution = append(summary.SizeDistribution, SizeBucket{Label: sizeBucketLabel(i), Count: count})
+	}
+	if stats.chunkCount > 0 {
+		summary.ChunkCount = stats.chunkCount
+		summary.AverageChunkTime = stats.chunkTime / time.Duration(stats.chunkCount)
+	}
+	if len(stats.malformed) > 0 {
+		summary.MalformedResponses = make(map[string]map[int]int, len(stats.malformed))
+		for corruption, codes := range stats.malformed {
+			summary.MalformedResponses[corruption] = make(map[int]int, len(codes))
+			for code, count := range codes {
+				summary.MalformedResponses[corruption][code] = count
+			}
+		}
+	}
+	if stats.verifiedCount > 0 {
+		summary.VerifiedCount = stats.verifiedCount
+		summary.VerifyFailures = stats.failureCategories[categoryVerify]
+	}
+	return summary
+}
+
+// RequestPhase aggregates one part of the requests that got a response:
+// upload is from sending until the body was written (including any wait
+// for a connection), server until the first response byte and download
+// until the response had been read.
+type RequestPhase struct {
+	Phase   string        `json:"phase"`
+	Average time.Duration `json:"average_ns"`
+	P50     time.Duration `json:"p50_ns"`
+	P99     time.Duration `json:"p99_ns"`
+}
+
+func newRequestPhase(phase string, durations *durationSample) RequestPhase {
+	marks := percentiles(durations.values, 50, 99)
+	return RequestPhase{Phase: phase, Average: durations.average(), P50: marks[0], P99: marks[1]}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// maxErrorBody caps how much of a failed response is kept for diagnostics.
+const maxErrorBody = 2 << 10
+
+// isTransient reports failures that usually clear up on their own, which
+// -stop-on-error lets through.
+func isTransient(result RequestResult) bool {
+	if result.Category == categoryConnection || result.Category == categoryTimeout {
+		return true
+	}
+	switch result.StatusCode {
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+func printFailureDetails(job uploadJob, result RequestResult) {
+	fmt.Printf("\n=== Stopping on first error ===\n")
+	fmt.Printf("Request:  %d\n", result.RequestNum)
+	fmt.Printf("URL:      %s\n", job.url)
+	fmt.Printf("Image:    %s (%d bytes)\n", result.ImageName, len(job.image.data))
+	if result.TraceID != "" {
+		fmt.Printf("Trace ID: %s\n", result.TraceID)
+	}
+	fmt.Printf("Status:   %d\n", result.StatusCode)
+	fmt.Printf("Category: %s\n", result.Category)
+	fmt.Printf("Duration: %v\n", result.Duration)
+	fmt.Printf("Error:    %v\n", result.Err)
+	if result.ResponseBody != "" {
+		fmt.Printf("Response body (first %d bytes):\n%s\n", maxErrorBody, result.ResponseBody)
+	}
+	fmt.Println()
+}
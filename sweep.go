@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// A level is past the knee when it adds less than sweepPlateauGain to the
+// previous level's RPS, or when its p99 exceeds sweepP99Degradation times
+// the p99 of the first level.
+const (
+	sweepPlateauGain    = 0.10
+	sweepP99Degradation = 2.0
+)
+
+// parseSweepLevels reads a comma-separated list of increasing concurrency
+// levels such as 1,2,5,10.
+func parseSweepLevels(value string) ([]int, error) {
+	var levels []int
+	for _, field := range strings.Split(value, ",") {
+		level, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil || level < 1 {
+			return nil, fmt.Errorf("expected positive concurrency levels such as 1,2,5,10, got %q", field)
+		}
+		if len(levels) > 0 && level <= levels[len(levels)-1] {
+			return nil, fmt.Errorf("levels must increase, got %d after %d", level, levels[len(levels)-1])
+		}
+		levels = append(levels, level)
+	}
+	return levels, nil
+}
+
+type sweepLevelStats struct {
+	latencies []time.Duration
+	requests  int
+	failures  int
+}
+
+// concurrencySweep resizes the pool through its levels, as SIGUSR1 would,
+// holding each for step. Requests are attributed to the level in effect
+// when they complete, so the few still in flight at a step boundary count
+// towards the next level. Neutral outcomes, such as the requests canceled
+// when the sweep ends, are left out.
+type concurrencySweep struct {
+	levels []int
+	step   time.Duration
+
+	mutex    sync.Mutex
+	current  int
+	finished bool
+	stats    []sweepLevelStats
+}
+
+func newConcurrencySweep(levels []int, step time.Duration) *concurrencySweep {
+	return &concurrencySweep{levels: levels, step: step, stats: make([]sweepLevelStats, len(levels))}
+}
+
+func (sweep *concurrencySweep) record(result RequestResult) {
+	sweep.mutex.Lock()
+	defer sweep.mutex.Unlock()
+	if sweep.finished || result.Neutral {
+		return
+	}
+	level := &sweep.stats[sweep.current]
+	level.requests++
+	if result.Success {
+		level.latencies = append(level.latencies, result.Duration)
+	} else {
+		level.failures++
+	}
+}
+
+// start steps through the levels, moving phase along with them, and calls
+// onDone, which should stop the run, once the last one has had its step.
+func (sweep *concurrencySweep) start(ctx context.Context, pool *workerPool, phase *runPhase, onDone func()) {
+	go func() {
+		for i, level := range sweep.levels {
+			sweep.mutex.Lock()
+			sweep.current = i
+			sweep.mutex.Unlock()
+			pool.resize(level)
+			phase.set(sweepPhase(level))
+			fmt.Printf("Sweep: concurrency %d for %v (%d of %d)\n", level, sweep.step, i+1, len(sweep.levels))
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(sweep.step):
+			}
+		}
+		sweep.mutex.Lock()
+		sweep.finished = true
+		sweep.mutex.Unlock()
+		onDone()
+	}()
+}
+
+type SweepLevel struct {
+	Concurrency int           `json:"concurrency"`
+	Requests    int           `json:"requests"`
+	Failures    int           `json:"failures"`
+	RPS         float64       `json:"rps"`
+	P99         time.Duration `json:"p99_ns"`
+}
+
+// report returns one row per level reached, with the levels where RPS
+// plateaus and where p99 degrades, or 0 when the sweep never got there.
+func (sweep *concurrencySweep) report() (levels []SweepLevel, plateau int, p99Knee int) {
+	sweep.mutex.Lock()
+	defer sweep.mutex.Unlock()
+
+	for i := range sweep.current + 1 {
+		stats := sweep.stats[i]
+		level := SweepLevel{
+			Concurrency: sweep.levels[i],
+			Requests:    stats.requests,
+			Failures:    stats.failures,
+			RPS:         float64(stats.requests) / sweep.step.Seconds(),
+			P99:         percentiles(stats.latencies, 99)[0],
+		}
+		if i > 0 {
+			previous := levels[i-1]
+			if plateau == 0 && level.RPS < previous.RPS*(1+sweepPlateauGain) {
+				plateau = level.Concurrency
+			}
+			if p99Knee == 0 && levels[0].P99 > 0 && float64(level.P99) > float64(levels[0].P99)*sweepP99Degradation {
+				p99Knee = level.Concurrency
+			}
+		}
+		levels = append(levels, level)
+	}
+	return levels, plateau, p99Knee
+}
+
+// sweepCSVReporter writes the sweep table from the summary, one row per
+// level, for charting RPS and p99 against concurrency.
+type sweepCSVReporter struct {
+	path string
+}
+
+func (reporter *sweepCSVReporter) RecordRequest(result RequestResult) {}
+
+func (reporter *sweepCSVReporter) Finish(summary Summary) error {
+	file, err := os.Create(reporter.path)
+	if err != nil {
+		return fmt.Errorf("error creating sweep CSV file: %v", err)
+	}
+	writer := csv.NewWriter(file)
+	writer.Write([]string{"concurrency", "requests", "failures", "rps", "p99_ms"})
+	for _, level := range summary.Sweep {
+		writer.Write([]string{
+			strconv.Itoa(level.Concurrency),
+			strconv.Itoa(level.Requests),
+			strconv.Itoa(level.Failures),
+			strconv.FormatFloat(level.RPS, 'f', 2, 64),
+			strconv.FormatFloat(float64(level.P99)/float64(time.Millisecond), 'f', 3, 64),
+		})
+	}
+	writer.Flush()
+	if err := writer.Error(); err != nil {
+		file.Close()
+		return fmt.Errorf("error writing sweep CSV file: %v", err)
+	}
+	return file.Close()
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"math/rand/v2"
+)
+
+// syntheticAttempts bounds how often the dimensions are rescaled towards the
+// requested size; the encoders' output size is only roughly predictable.
+const syntheticAttempts = 5
+
+// generateSyntheticImage encodes a noise image so that the payload is close
+// to size. Noise keeps the encoders from compressing it away, so the bytes on
+// the wire grow with the pixel count. Fixed dimensions skip the size search.
+func generateSyntheticImage(size int64, format string, width, height int) (ImageFile, error) {
+	if width == 0 {
+		// Start from about three bytes per pixel and correct from there.
+		width = max(1, int(math.Sqrt(float64(size)/3)))
+		height = width
+		for attempt := 1; attempt < syntheticAttempts; attempt++ {
+			data, err := encodeSyntheticImage(format, width, height)
+			if err != nil {
+				return ImageFile{}, err
+			}
+			ratio := float64(size) / float64(len(data))
+			if math.Abs(ratio-1) < 0.02 {
+				break
+			}
+			width = max(1, int(float64(width)*math.Sqrt(ratio)))
+			height = width
+		}
+	}
+
+	data, err := encodeSyntheticImage(format, width, height)
+	if err != nil {
+		return ImageFile{}, err
+	}
+	image := ImageFile{
+		name:        fmt.Sprintf("synthetic-%dx%d.%s", width, height, extensionForFormat(format)),
+		data:        data,
+		contentType: "image/" + format,
+	}
+	return image, nil
+}
+
+func encodeSyntheticImage(format string, width, height int) ([]byte, error) {
+	// A fixed seed gives the same bytes every run, so runs stay comparable.
+	random := rand.New(rand.NewPCG(1, 2))
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			value := random.Uint32()
+			img.SetRGBA(x, y, color.RGBA{R: uint8(value), G: uint8(value >> 8), B: uint8(value >> 16), A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	default:
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 90})
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error encoding synthetic %s: %v", format, err)
+	}
+	return buf.Bytes(), nil
+}
+
+func extensionForFormat(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
+
+// parseDimensions reads a WxH value such as 640x480.
+func parseDimensions(value string) (int, int, error) {
+	var width, height int
+	if _, err := fmt.Sscanf(value, "%dx%d", &width, &height); err != nil || width <= 0 || height <= 0 {
+		return 0, 0, fmt.Errorf("expected WxH with positive sides, got %q", value)
+	}
+	return width, height, nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"text/template"
+)
+
+const (
+	categoryTemplate    = "template"
+	categoryURLTemplate = "url_template"
+)
+
+type templateData struct {
+	Index     int
+	ImageName string
+	TraceID   string
+}
+
+// bodyTemplate renders extra form fields for each request. The output is
+// either key=value lines or a JSON object whose keys become fields.
+type bodyTemplate struct {
+	tmpl *template.Template
+}
+
+func newBodyTemplate(text string, random *lockedRand) (*bodyTemplate, error) {
+	if strings.HasPrefix(text, "@") {
+		data, err := os.ReadFile(text[1:])
+		if err != nil {
+			return nil, fmt.Errorf("error reading body template: %v", err)
+		}
+		text = string(data)
+	}
+
+	tmpl, err := template.New("body").Funcs(templateFuncs(random)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing body template: %v", err)
+	}
+	return &bodyTemplate{tmpl: tmpl}, nil
+}
+
+// templateFuncs are the helpers shared by -body-template and -url-template.
+func templateFuncs(random *lockedRand) template.FuncMap {
+	return template.FuncMap{
+		// randInt returns a value in [min, max).
+		"randInt": func(min int, max int) (int, error) {
+			if max <= min {
+				return 0, fmt.Errorf("randInt: max %d must be greater than min %d", max, min)
+			}
+			return min + random.IntN(max-min), nil
+		},
+		"uuid": newTraceID,
+	}
+}
+
+func newTemplateData(job uploadJob) templateData {
+	return templateData{Index: job.requestNum, ImageName: job.image.name, TraceID: job.traceID}
+}
+
+func (body *bodyTemplate) render(job uploadJob) ([]formField, error) {
+	var out bytes.Buffer
+	if err := body.tmpl.Execute(&out, newTemplateData(job)); err != nil {
+		return nil, err
+	}
+
+	rendered := bytes.TrimSpace(out.Bytes())
+	if bytes.HasPrefix(rendered, []byte("{")) {
+		return parseTemplateJSON(rendered)
+	}
+
+	var fields []formField
+	scanner := bufio.NewScanner(bytes.NewReader(rendered))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("rendered line %q is not key=value", line)
+		}
+		fields = append(fields, formField{key: key, value: value})
+	}
+	return fields, nil
+}
+
+func parseTemplateJSON(rendered []byte) ([]formField, error) {
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(rendered, &object); err != nil {
+		return nil, fmt.Errorf("rendered JSON is invalid: %v", err)
+	}
+
+	fields := make([]formField, 0, len(object))
+	for key, raw := range object {
+		var value string
+		if err := json.Unmarshal(raw, &value); err != nil {
+			value = string(raw)
+		}
+		fields = append(fields, formField{key: key, value: value})
+	}
+	return fields, nil
+}
+
+// urlTemplate renders the target URL of each request, e.g.
+// https://host/faces/{{uuid}} or .../{{.ImageName | urlquery}}.
+type urlTemplate struct {
+	tmpl *template.Template
+}
+
+func newURLTemplate(text string, random *lockedRand) (*urlTemplate, error) {
+	tmpl, err := template.New("url").Funcs(templateFuncs(random)).Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing URL template: %v", err)
+	}
+	return &urlTemplate{tmpl: tmpl}, nil
+}
+
+// render checks every rendered URL, since a value such as an image name can
+// make one render invalid while the rest are fine.
+func (target *urlTemplate) render(job uploadJob) (string, error) {
+	var out strings.Builder
+	if err := target.tmpl.Execute(&out, newTemplateData(job)); err != nil {
+		return "", err
+	}
+	rendered := strings.TrimSpace(out.String())
+	parsed, err := url.Parse(rendered)
+	if err != nil {
+		return "", fmt.Errorf("rendered URL %q is invalid: %v", rendered, err)
+	}
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return "", fmt.Errorf("rendered URL %q is not an absolute http or https URL", rendered)
+	}
+	return rendered, nil
+}
@@ -0,0 +1,21 @@
+package main
+
+import (
+	"crypto/rand"
+	"fmt"
+)
+
+func newTraceID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40
+	id[8] = id[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", id[0:4], id[4:6], id[6:8], id[8:10], id[10:16])
+}
+
+func (job uploadJob) label() string {
+	if job.traceID == "" {
+		return fmt.Sprintf("Request %d", job.requestNum)
+	}
+	return fmt.Sprintf("Request %d [%s]", job.requestNum, job.traceID)
+}
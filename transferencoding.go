@@ -0,0 +1,12 @@
+package main
+
+import "net/http"
+
+const categoryChunkedRejected = "chunked_rejected"
+
+// classifyChunkedRejection files 411 Length Required under its own category
+// with -chunked: the server or a proxy in front of it refuses bodies sent
+// without a Content-Length.
+func classifyChunkedRejection(statusCode int, _ http.Header, _ []byte) (string, bool) {
+	return categoryChunkedRejected, statusCode == http.StatusLengthRequired
+}
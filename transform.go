@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const encryptionHeader = "X-Encryption"
+
+func parseTransforms(spec string) ([]string, error) {
+	var transforms []string
+	for _, name := range strings.Split(spec, ",") {
+		name = strings.TrimSpace(name)
+		switch name {
+		case "":
+		case "gzip", "aes":
+			transforms = append(transforms, name)
+		default:
+			return nil, fmt.Errorf("unknown transform %q (supported: gzip, aes)", name)
+		}
+	}
+	return transforms, nil
+}
+
+func loadTransformKey(hexKey string, keyFile string) ([]byte, error) {
+	var key []byte
+	switch {
+	case hexKey != "" && keyFile != "":
+		return nil, fmt.Errorf("only one of -transform-key or -transform-key-file can be specified")
+	case hexKey != "":
+		var err error
+		key, err = hex.DecodeString(hexKey)
+		if err != nil {
+			return nil, fmt.Errorf("-transform-key must be hex: %v", err)
+		}
+	case keyFile != "":
+		var err error
+		key, err = os.ReadFile(keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error reading key file: %v", err)
+		}
+	default:
+		return nil, fmt.Errorf("the aes transform needs -transform-key or -transform-key-file")
+	}
+
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, fmt.Errorf("AES key must be 16, 24 or 32 bytes, got %d", len(key))
+	}
+	return key, nil
+}
+
+func applyTransforms(images []ImageFile, transforms []string, key []byte) (int64, int64, error) {
+	var before, after int64
+	for i := range images {
+		before += int64(len(images[i].data))
+		for _, name := range transforms {
+			var err error
+			switch name {
+			case "gzip":
+				images[i].data, err = gzipBytes(images[i].data)
+				images[i].contentEncoding = "gzip"
+			case "aes":
+				images[i].data, err = encryptBytes(images[i].data, key)
+			}
+			if err != nil {
+				return 0, 0, fmt.Errorf("error applying %s to %s: %v", name, images[i].name, err)
+			}
+		}
+		after += int64(len(images[i].data))
+	}
+	return before, after, nil
+}
+
+func gzipBytes(data []byte) ([]byte, error) {
+	var out bytes.Buffer
+	writer := gzip.NewWriter(&out)
+	if _, err := writer.Write(data); err != nil {
+		return nil, err
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+// encryptBytes uses AES-GCM and prepends the random nonce to the ciphertext.
+func encryptBytes(data []byte, key []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return gcm.Seal(nonce, nonce, data, nil), nil
+}
@@ -0,0 +1,47 @@
+//go:build linux || darwin
+
+package main
+
+import (
+	"fmt"
+	"syscall"
+)
+
+// openFileOverhead covers descriptors outside the workers' connections:
+// stdio, output files, the Docker socket and spare keep-alive connections.
+const openFileOverhead = 64
+
+// checkOpenFiles compares RLIMIT_NOFILE with what the run needs, so a high
+// -concurrency fails with a clear warning instead of "too many open files"
+// halfway through. The Go runtime already lifts the soft limit to the hard
+// one at startup; raising the hard limit needs privileges.
+func checkOpenFiles(concurrency int, raise bool) {
+	// Two per worker: the upload connection and a -verify-get or redial
+	// that is still closing.
+	need := uint64(2*concurrency + openFileOverhead)
+
+	var limit syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+		fmt.Printf("Warning: couldn't read the open file limit: %v\n", err)
+		return
+	}
+
+	if limit.Cur < need && raise {
+		wanted := syscall.Rlimit{Cur: need, Max: max(limit.Max, need)}
+		if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &wanted); err != nil {
+			fmt.Printf("Warning: couldn't raise the open file limit to %d: %v\n", need, err)
+		} else if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &limit); err != nil {
+			fmt.Printf("Warning: couldn't read the open file limit: %v\n", err)
+			return
+		}
+	}
+
+	fmt.Printf("Open file limit: %d (hard %d), about %d needed\n", limit.Cur, limit.Max, need)
+	if limit.Cur < need {
+		hint := "lower -concurrency, raise ulimit -n or pass -raise-ulimit"
+		if raise {
+			hint = "lower -concurrency or raise the hard limit as root"
+		}
+		fmt.Printf("Warning: open file limit %d is below the %d this run needs; %s\n", limit.Cur, need, hint)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+)
+
+func checkImageDecodes(img ImageFile) error {
+	_, _, err := image.DecodeConfig(bytes.NewReader(img.data))
+	return err
+}
+
+func validateImages(images []ImageFile) ([]ImageFile, int) {
+	valid := images[:0:0]
+	dropped := 0
+	for _, img := range images {
+		if err := checkImageDecodes(img); err != nil {
+			fmt.Printf("Warning: dropping undecodable image %s: %v\n", img.name, err)
+			dropped++
+			continue
+		}
+		valid = append(valid, img)
+	}
+	return valid, dropped
+}
+
+func validatePlan(plan []planEntry) ([]planEntry, int) {
+	valid := plan[:0:0]
+	dropped := 0
+	for _, entry := range plan {
+		if err := checkImageDecodes(entry.image); err != nil {
+			fmt.Printf("Warning: dropping plan row with undecodable image %s: %v\n", entry.image.name, err)
+			dropped++
+			continue
+		}
+		valid = append(valid, entry)
+	}
+	return valid, dropped
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+const categoryVerify = "verify"
+
+// maxVerifyBody caps how much of an upload response is kept for ID parsing.
+const maxVerifyBody = 1 << 20
+
+// parseUploadID takes the "id" field from a JSON upload response. The bulk
+// endpoint answers with an array, so the first element is used in that case.
+func parseUploadID(body []byte) (string, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+
+	var response any
+	if err := decoder.Decode(&response); err != nil {
+		return "", fmt.Errorf("error decoding upload response: %v", err)
+	}
+	if list, ok := response.([]any); ok && len(list) > 0 {
+		response = list[0]
+	}
+
+	object, ok := response.(map[string]any)
+	if !ok {
+		return "", fmt.Errorf("upload response is not a JSON object")
+	}
+	switch id := object["id"].(type) {
+	case string:
+		return id, nil
+	case json.Number:
+		return id.String(), nil
+	default:
+		return "", fmt.Errorf("upload response has no id field")
+	}
+}
+
+func verifyUpload(ctx context.Context, client *http.Client, cfg *Config, job uploadJob, body []byte, bearerToken string) error {
+	id, err := parseUploadID(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "GET", strings.ReplaceAll(cfg.VerifyGet, "{id}", url.PathEscape(id)), nil)
+	if err != nil {
+		return fmt.Errorf("error creating verify request: %v", err)
+	}
+	req.Header.Set("User-Agent", cfg.UserAgent)
+	req.Header.Set("Authorization", "Bearer "+bearerToken)
+	if job.traceID != "" {
+		req.Header.Set(cfg.TraceHeader, job.traceID)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("verify GET %s: %v", req.URL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("verify GET %s returned status %d", req.URL, resp.StatusCode)
+	}
+	return nil
+}
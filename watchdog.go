@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"time"
+)
+
+// startWatchdog dumps every goroutine's stack when requests are in flight
+// but none has completed for timeout, and exits if abort is set. Unlike the
+// stall detector it fires with any number of workers busy.
+func startWatchdog(pool *workerPool, timeout time.Duration, abort bool) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(checkInterval(timeout))
+		defer ticker.Stop()
+
+		lastCompleted := pool.completedCount()
+		lastProgress := time.Now()
+		fired := false
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+
+			completed := pool.completedCount()
+			if completed != lastCompleted || pool.inFlight() == 0 {
+				lastCompleted = completed
+				lastProgress = time.Now()
+				fired = false
+				continue
+			}
+			if fired || time.Since(lastProgress) < timeout {
+				continue
+			}
+
+			fired = true
+			if abort {
+				// The dump is the last thing the run prints.
+				restoreTerminal()
+			}
+			fmt.Printf("Watchdog: %d requests in flight and none completed for %v, goroutine dump follows\n",
+				pool.inFlight(), time.Since(lastProgress).Round(time.Second))
+			fmt.Printf("%s\n", goroutineStacks())
+			if abort {
+				fmt.Printf("Watchdog: aborting\n")
+				os.Exit(exitWatchdog)
+			}
+		}
+	}()
+
+	return func() { close(done) }
+}
+
+func goroutineStacks() []byte {
+	buf := make([]byte, 1<<20)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			return buf[:n]
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+}
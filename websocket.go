@@ -0,0 +1,143 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// wsUploader sends each image as one binary frame and waits for any reply
+// as the ack. Every worker keeps its own connection and only redials after
+// an error, so frames on one connection never interleave.
+type wsUploader struct {
+	cfg         *Config
+	bearerToken string
+
+	mutex  sync.Mutex
+	conns  map[int]*websocket.Conn
+	dials  int
+	reuses int
+}
+
+func newWSUploader(cfg *Config, bearerToken string) *wsUploader {
+	return &wsUploader{cfg: cfg, bearerToken: bearerToken, conns: make(map[int]*websocket.Conn)}
+}
+
+func (uploader *wsUploader) conn(ctx context.Context, workerID int, job uploadJob) (*websocket.Conn, error) {
+	uploader.mutex.Lock()
+	conn := uploader.conns[workerID]
+	if conn != nil {
+		uploader.reuses++
+	}
+	uploader.mutex.Unlock()
+	if conn != nil {
+		return conn, nil
+	}
+
+	header := http.Header{}
+	header.Set("User-Agent", uploader.cfg.UserAgent)
+	header.Set("Authorization", "Bearer "+uploader.bearerToken)
+	if job.traceID != "" {
+		header.Set(uploader.cfg.TraceHeader, job.traceID)
+	}
+
+	// The dialer only honours ctx's deadline during the handshake, so
+	// cancellation moves the socket deadline to now instead. This watches
+	// ctx itself: the dialer cancels its own dialCtx once it returns.
+	stopCancel := func() bool { return false }
+	dialer := *websocket.DefaultDialer
+	dialer.NetDialContext = func(dialCtx context.Context, network string, addr string) (net.Conn, error) {
+		netConn, err := (&net.Dialer{}).DialContext(dialCtx, network, addr)
+		if err == nil {
+			stopCancel = context.AfterFunc(ctx, func() { netConn.SetDeadline(time.Now()) })
+		}
+		return netConn, err
+	}
+	conn, _, err := dialer.DialContext(ctx, job.url, header)
+	stopCancel()
+	if err != nil {
+		return nil, err
+	}
+
+	uploader.mutex.Lock()
+	uploader.conns[workerID] = conn
+	uploader.dials++
+	uploader.mutex.Unlock()
+	return conn, nil
+}
+
+func (uploader *wsUploader) drop(workerID int) {
+	uploader.mutex.Lock()
+	defer uploader.mutex.Unlock()
+	if conn := uploader.conns[workerID]; conn != nil {
+		conn.Close()
+		delete(uploader.conns, workerID)
+	}
+}
+
+// makeRequest sends one frame and waits for the ack. Canceling ctx, as
+// -stop-on-error or -max-runtime do, moves the socket deadlines to now so
+// neither the write nor the read outlives the run.
+func (uploader *wsUploader) makeRequest(ctx context.Context, workerID int, job uploadJob) RequestResult {
+	result := RequestResult{RequestNum: job.requestNum, ImageName: job.image.name, TraceID: job.traceID, Images: 1, PayloadBytes: int64(len(job.image.data))}
+
+	conn, err := uploader.conn(ctx, workerID, job)
+	if err != nil {
+		if ctx.Err() != nil {
+			result.Category, result.Neutral = categoryCanceled, true
+			result.Err = err
+			return result
+		}
+		fmt.Printf("%s failed to connect: %v\n", job.label(), err)
+		result.Category = categoryConnection
+		result.Err = err
+		return result
+	}
+
+	startTime := time.Now()
+	conn.SetWriteDeadline(startTime.Add(requestTimeout))
+	conn.SetReadDeadline(startTime.Add(requestTimeout))
+	stopCancel := context.AfterFunc(ctx, func() {
+		conn.SetWriteDeadline(time.Now())
+		conn.SetReadDeadline(time.Now())
+	})
+	if err = conn.WriteMessage(websocket.BinaryMessage, job.image.data); err == nil {
+		_, _, err = conn.ReadMessage()
+	}
+	stopCancel()
+	result.Duration = time.Since(startTime)
+	if err != nil {
+		uploader.drop(workerID)
+		result.Err = err
+		if ctx.Err() != nil {
+			result.Category, result.Neutral = categoryCanceled, true
+			return result
+		}
+		fmt.Printf("%s failed: %v\n", job.label(), err)
+		result.Category = categoryConnection
+		return result
+	}
+
+	result.BytesSent = int64(len(job.image.data))
+	result.Success = true
+	if job.requestNum%50 == 0 {
+		fmt.Printf("%s acknowledged in %v\n", job.label(), result.Duration)
+	}
+	return result
+}
+
+func (uploader *wsUploader) close() (dials int, reuses int) {
+	uploader.mutex.Lock()
+	defer uploader.mutex.Unlock()
+	for workerID, conn := range uploader.conns {
+		conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, ""), time.Now().Add(time.Second))
+		conn.Close()
+		delete(uploader.conns, workerID)
+	}
+	return uploader.dials, uploader.reuses
+}
@@ -0,0 +1,60 @@
+package main
+
+import "sort"
+
+type weightedSelector struct {
+	cumulative []float64
+	random     *lockedRand
+}
+
+func newSizeWeightedSelector(images []ImageFile, mode string, random *lockedRand) *weightedSelector {
+	selector := &weightedSelector{cumulative: make([]float64, len(images)), random: random}
+	total := 0.0
+	for i, image := range images {
+		size := float64(max(len(image.data), 1))
+		if mode == "inverse" {
+			total += 1 / size
+		} else {
+			total += size
+		}
+		selector.cumulative[i] = total
+	}
+	return selector
+}
+
+func (selector *weightedSelector) pick() int {
+	target := selector.random.Float64() * selector.cumulative[len(selector.cumulative)-1]
+	index := sort.SearchFloat64s(selector.cumulative, target)
+	return min(index, len(selector.cumulative)-1)
+}
+
+type SizeBucket struct {
+	Label string `json:"label"`
+	Count int    `json:"count"`
+}
+
+var sizeBucketBounds = []struct {
+	label string
+	limit int
+}{
+	{"<100KB", 100 << 10},
+	{"100KB-500KB", 500 << 10},
+	{"500KB-1MB", 1 << 20},
+	{"1MB-5MB", 5 << 20},
+}
+
+func sizeBucketIndex(size int) int {
+	for i, bound := range sizeBucketBounds {
+		if size < bound.limit {
+			return i
+		}
+	}
+	return len(sizeBucketBounds)
+}
+
+func sizeBucketLabel(index int) string {
+	if index < len(sizeBucketBounds) {
+		return sizeBucketBounds[index].label
+	}
+	return ">=5MB"
+}